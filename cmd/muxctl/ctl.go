@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/xunzhou/muxctl/pkg/ctl"
+	"github.com/xunzhou/muxctl/pkg/tmux"
+)
+
+// subcommandUsage lists the subcommands that script a running muxctl
+const subcommandUsage = `Usage:
+  muxctl [-control] [-config file]      start the TUI
+         [-L name | -S path]            on another tmux server
+  muxctl list                           list resources and AI chats
+  muxctl attach <id>                    show a resource or AI chat
+  muxctl ai new [--backend name]        start a new AI chat
+         [--resume session]             resuming a backend session
+  muxctl close <id>                     close a resource or AI chat pane
+  muxctl rename <id> <title>            title an AI chat ("" to clear)
+  muxctl send <id> -- <keys>...         send keys (send-keys syntax)
+  muxctl capture <id>                   print the contents of a pane
+  muxctl click <pane>                   handle a status bar tab click
+  muxctl replay [-speed n] <file>       play back a recording or .cast file
+  muxctl export <log> [out.cast]        convert a recording to asciinema v2
+  muxctl redact [-config file] [-apply]  preview secrets redacted from a file
+         [file]                         or stdin (-apply prints the result)
+
+Subcommands accept --json for machine-readable output and --session to
+address a muxctl in another tmux session. -L and -S before the subcommand
+pick the tmux server, as they do for tmux.
+`
+
+// runSubcommand sends a subcommand to the running muxctl and returns the
+// process exit code
+func runSubcommand(args []string, serverOpts []tmux.Option) int {
+	fs := flag.NewFlagSet("muxctl "+args[0], flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "print the response as JSON")
+	session := fs.String("session", "", "tmux session of the running muxctl (default: the current one)")
+	backend := fs.String("backend", "", "ai new: AI backend to use (default: the one selected in the TUI)")
+	resume := fs.String("resume", "", "ai new: backend session ID to resume")
+	fs.Usage = func() { fmt.Fprint(fs.Output(), subcommandUsage) }
+
+	positional, keys, err := parseSubcommandArgs(fs, args[1:])
+	if err != nil {
+		return 2
+	}
+
+	req, err := subcommandRequest(args[0], positional, keys)
+	if err == nil && (*backend != "" || *resume != "") {
+		if req.Action != ctl.ActionAINew {
+			err = fmt.Errorf("--backend and --resume only apply to ai new")
+		}
+		req.Backend, req.Resume = *backend, *resume
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n%s", err, subcommandUsage)
+		return 2
+	}
+
+	if *session == "" && os.Getenv("TMUX") == "" {
+		fmt.Fprintln(os.Stderr, "Error: not inside tmux; use --session to pick a session")
+		return 1
+	}
+	path, err := ctlSocketPath(tmux.NewRunner(serverOpts...), *session)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	resp, err := ctl.Do(path, req)
+	if *jsonOutput && resp != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(resp)
+	}
+	if err != nil {
+		if !*jsonOutput || resp == nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		return 1
+	}
+	if !*jsonOutput {
+		printResponse(os.Stdout, req, resp)
+	}
+	return 0
+}
+
+// ctlSocketPath returns the control socket the muxctl running in a session
+// published ("" = the current session)
+func ctlSocketPath(runner tmux.Runner, session string) (string, error) {
+	args := []string{"display-message", "-p"}
+	if session != "" {
+		// "=" matches the session name exactly instead of as a prefix
+		args = append(args, "-t", "="+session)
+	}
+	path, err := runner.Run(append(args, "#{"+ctl.SocketOption+"}")...)
+	if err != nil {
+		return "", fmt.Errorf("find muxctl's control socket: %w", err)
+	}
+	if path == "" {
+		return "", fmt.Errorf("muxctl is not running in this session")
+	}
+	return path, nil
+}
+
+// parseSubcommandArgs parses flags anywhere before a "--" separator and
+// returns the positional arguments and everything after the separator
+func parseSubcommandArgs(fs *flag.FlagSet, args []string) ([]string, []string, error) {
+	var keys []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, keys = args[:i], args[i+1:]
+			break
+		}
+	}
+
+	// The flag package stops at the first positional argument, so resume
+	// after each one to allow "muxctl attach pod-a --json"
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, keys, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// subcommandRequest builds the request for a subcommand
+func subcommandRequest(name string, positional, keys []string) (*ctl.Request, error) {
+	wantArgs := func(n int) error {
+		if len(positional) != n {
+			return fmt.Errorf("%s takes %d argument(s), got %d", name, n, len(positional))
+		}
+		return nil
+	}
+
+	var req ctl.Request
+	switch name {
+	case "list":
+		req.Action = ctl.ActionList
+		return &req, wantArgs(0)
+
+	case "ai":
+		if len(positional) == 0 || positional[0] != "new" {
+			return nil, fmt.Errorf("unknown ai subcommand %q", strings.Join(positional, " "))
+		}
+		req.Action = ctl.ActionAINew
+		positional = positional[1:]
+		return &req, wantArgs(0)
+
+	case "attach", "close", "capture":
+		req.Action = map[string]ctl.Action{
+			"attach":  ctl.ActionAttach,
+			"close":   ctl.ActionClose,
+			"capture": ctl.ActionCapture,
+		}[name]
+		if err := wantArgs(1); err != nil {
+			return nil, err
+		}
+		req.ID = positional[0]
+		return &req, nil
+
+	case "rename":
+		if err := wantArgs(2); err != nil {
+			return nil, err
+		}
+		req.Action = ctl.ActionRename
+		req.ID = positional[0]
+		req.Title = positional[1]
+		return &req, nil
+
+	case "click":
+		// Tabs are named after their pane number; see tmux.WithTabClickCommand
+		if err := wantArgs(1); err != nil {
+			return nil, err
+		}
+		req.Action = ctl.ActionAttach
+		req.ID = "%" + strings.TrimPrefix(positional[0], "%")
+		return &req, nil
+
+	case "send":
+		if err := wantArgs(1); err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("send needs keys after --")
+		}
+		req.Action = ctl.ActionSend
+		req.ID = positional[0]
+		req.Keys = keys
+		return &req, nil
+	}
+	return nil, fmt.Errorf("unknown command %q", name)
+}
+
+// printResponse prints a successful response in human-readable form
+func printResponse(w io.Writer, req *ctl.Request, resp *ctl.Response) {
+	switch req.Action {
+	case ctl.ActionList:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "KIND\tID\tSTATE\tNAME")
+		for _, item := range resp.Items {
+			state := "-"
+			if item.Active {
+				state = "active"
+			} else if item.PaneID != "" {
+				state = "open"
+			}
+			name := item.Name
+			if item.Kind == ctl.KindAI {
+				name = strings.TrimSpace(fmt.Sprintf("%s (%s)", item.Name, item.Backend))
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", item.Kind, item.ID, state, name)
+		}
+		tw.Flush()
+
+	case ctl.ActionAINew:
+		fmt.Fprintln(w, resp.ID)
+
+	case ctl.ActionCapture:
+		fmt.Fprint(w, resp.Content)
+		if resp.Content != "" && !strings.HasSuffix(resp.Content, "\n") {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// shellQuote quotes s as a single POSIX shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
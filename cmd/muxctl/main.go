@@ -1,15 +1,64 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/xunzhou/muxctl/internal"
+	"github.com/xunzhou/muxctl/pkg/ai"
+	"github.com/xunzhou/muxctl/pkg/config"
+	"github.com/xunzhou/muxctl/pkg/ctl"
+	"github.com/xunzhou/muxctl/pkg/redact"
+	"github.com/xunzhou/muxctl/pkg/socket"
 	"github.com/xunzhou/muxctl/pkg/tmux"
-	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
+	controlMode := flag.Bool("control", false, "talk to tmux over a single control-mode (-C) connection")
+	configPath := flag.String("config", "", "config file (default $XDG_CONFIG_HOME/muxctl/config.yaml)")
+	exitMode := flag.String("exit", "", "on exit: teardown, detach or kill-session (default from config, else teardown)")
+	serverName := flag.String("L", "", "tmux server socket name, as for tmux -L")
+	serverPath := flag.String("S", "", "tmux server socket path, as for tmux -S")
+	flag.Usage = func() { fmt.Fprint(flag.CommandLine.Output(), subcommandUsage) }
+	flag.Parse()
+
+	serverOpts, err := serverOptions(*serverName, *serverPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Subcommands script the muxctl already running in this session, apart
+	// from those working on recording files
+	if flag.NArg() > 0 {
+		if code, ok := runLocalSubcommand(flag.Args()); ok {
+			os.Exit(code)
+		}
+		os.Exit(runSubcommand(flag.Args(), serverOpts))
+	}
+
+	// Load resources before touching tmux so config errors leave the layout alone
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	redactor, err := redactorFromConfig(cfg.Redaction)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *exitMode != "" {
+		if err := config.CheckExitMode(*exitMode); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.ExitMode = *exitMode
+	}
+
 	// Check if running in tmux
 	if os.Getenv("TMUX") == "" {
 		fmt.Fprintln(os.Stderr, "Error: must run inside tmux")
@@ -18,7 +67,54 @@ func main() {
 	}
 
 	// Initialize tmux manager
-	mgr, err := tmux.NewManager()
+	opts := serverOpts
+	paneID := os.Getenv("TMUX_PANE")
+	if *controlMode {
+		// Attach the control client to the TUI's own pane so commands and
+		// notifications are scoped to this session
+		runner, err := tmux.NewControlRunner(paneID, tmux.ServerArgs(serverOpts...)...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error starting tmux control mode: %v\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, tmux.WithRunner(runner), tmux.WithTarget(paneID))
+	} else if len(serverOpts) > 0 {
+		// $TMUX only names the current pane to clients of its own server
+		opts = append(opts, tmux.WithTarget(paneID))
+	}
+
+	// Status bar tab clicks call back into this binary
+	if exe, err := os.Executable(); err == nil {
+		// #{q:...} shell-escapes the session name when tmux expands it
+		command := fmt.Sprintf("%s%s click --session #{q:session_name} #{mouse_status_range}",
+			shellQuote(exe), serverFlags(*serverName, *serverPath))
+		opts = append(opts, tmux.WithTabClickCommand(command))
+
+		// Recordings pipe pane output back into this binary as well, which
+		// redacts it with the same config
+		command = shellQuote(exe) + " " + recordPipeCommand
+		if *configPath != "" {
+			// pipe-pane runs in the pane's directory, not this one
+			path, err := filepath.Abs(*configPath)
+			if err != nil {
+				path = *configPath
+			}
+			command += " -config " + shellQuote(path)
+		}
+		opts = append(opts, tmux.WithRecordCommand(command))
+	}
+
+	opts = append(opts,
+		tmux.WithTheme(themeFromConfig(cfg.Theme)),
+		tmux.WithCaptureFilter(redactor.Redact),
+		tmux.WithExitMode(tmux.ExitMode(cfg.ExitMode)),
+		tmux.WithLayout(tmux.Layout{
+			Horizontal:     cfg.Layout.Orientation == config.OrientationHorizontal,
+			TUISize:        cfg.Layout.TUISize,
+			PreserveResize: cfg.Layout.PreserveResize,
+		}))
+
+	mgr, err := tmux.NewManager(opts...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing tmux: %v\n", err)
 		os.Exit(1)
@@ -30,8 +126,30 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Report pane exits as they happen instead of on the next tick
+	if err := mgr.StartEvents(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: pane lifecycle events unavailable: %v\n", err)
+	}
+
 	// Create Bubble Tea model
-	model := internal.NewModel(mgr)
+	model, err := internal.NewModel(mgr, cfg)
+	if err != nil {
+		mgr.Cleanup()
+		fmt.Fprintf(os.Stderr, "Error creating model: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Let external tools drive AI chats over a Unix socket
+	srv, err := startAIServer(mgr, model)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: AI conversation socket unavailable: %v\n", err)
+	}
+
+	// Let muxctl subcommands script this instance
+	ctlSrv, err := startCtlServer(mgr, model)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: control socket unavailable: %v\n", err)
+	}
 
 	// Run the program
 	p := tea.NewProgram(model, tea.WithAltScreen())
@@ -40,6 +158,154 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Cleanup
+	// Cleanup; requests still waiting for the TUI are answered with an error
+	model.Close()
+	if srv != nil {
+		srv.Close()
+		mgr.SetSessionOption(ai.SocketOption, "")
+	}
+	if ctlSrv != nil {
+		ctlSrv.Close()
+		mgr.SetSessionOption(ctl.SocketOption, "")
+	}
 	mgr.Cleanup()
 }
+
+// serverOptions selects the tmux server given by -L or -S; none means the one
+// in $TMUX
+func serverOptions(name, path string) ([]tmux.Option, error) {
+	switch {
+	case name != "" && path != "":
+		return nil, fmt.Errorf("-L and -S are mutually exclusive")
+	case name != "":
+		return []tmux.Option{tmux.WithSocketName(name)}, nil
+	case path != "":
+		return []tmux.Option{tmux.WithSocketPath(path)}, nil
+	}
+	return nil, nil
+}
+
+// serverFlags returns the -L or -S flag, with a leading space, that makes a
+// command run by tmux address the same server as this one
+func serverFlags(name, path string) string {
+	switch {
+	case name != "":
+		return " -L " + shellQuote(name)
+	case path != "":
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		return " -S " + shellQuote(path)
+	}
+	return ""
+}
+
+// themeFromConfig converts the configured theme, applying its overrides to
+// the preset
+func themeFromConfig(t config.Theme) tmux.Theme {
+	theme, ok := tmux.ThemePreset(t.Preset)
+	if !ok {
+		theme = tmux.DarkTheme
+	}
+	for _, o := range []struct {
+		dst *string
+		src string
+	}{
+		{&theme.StatusStyle, t.StatusStyle},
+		{&theme.BorderStyle, t.BorderStyle},
+		{&theme.ActiveBorderStyle, t.ActiveBorderStyle},
+		{&theme.ActiveTab, t.ActiveTab},
+		{&theme.InactiveTab, t.InactiveTab},
+		{&theme.DimTab, t.DimTab},
+		{&theme.RecordingTab, t.RecordingTab},
+	} {
+		if o.src != "" {
+			*o.dst = o.src
+		}
+	}
+	return theme
+}
+
+// redactorFromConfig builds the secret redactor from the builtin detectors
+// the config leaves enabled followed by its own rules
+func redactorFromConfig(r config.Redaction) (*redact.Redactor, error) {
+	disabled := make(map[string]bool)
+	for _, name := range r.Disable {
+		disabled[name] = true
+	}
+
+	var rules []redact.Rule
+	for _, rule := range redact.Builtin() {
+		if disabled[rule.Name] {
+			delete(disabled, rule.Name)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	for name := range disabled {
+		return nil, fmt.Errorf("unknown builtin redaction rule %q", name)
+	}
+
+	for _, rc := range r.Rules {
+		rule, err := redact.Compile(rc.Name, rc.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return redact.New(rules...), nil
+}
+
+// startAIServer serves AI conversation requests on the session's socket
+func startAIServer(mgr *tmux.Manager, handler ai.ConversationHandler) (*socket.Server, error) {
+	path, err := socketPath(mgr, ai.SocketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := ai.Listen(path, handler)
+	if err != nil {
+		return nil, err
+	}
+	go srv.Serve()
+	publishSocket(mgr, ai.SocketOption, path)
+	return srv, nil
+}
+
+// startCtlServer serves muxctl subcommands on the session's control socket
+func startCtlServer(mgr *tmux.Manager, handler ctl.Handler) (*socket.Server, error) {
+	path, err := socketPath(mgr, ctl.SocketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := ctl.Listen(path, handler)
+	if err != nil {
+		return nil, err
+	}
+	go srv.Serve()
+	publishSocket(mgr, ctl.SocketOption, path)
+	return srv, nil
+}
+
+// socketPath returns the path pathFor gives this instance's session and
+// tmux server
+func socketPath(mgr *tmux.Manager, pathFor func(serverSocket, sessionName string) (string, error)) (string, error) {
+	serverSocket, err := mgr.ServerSocket()
+	if err != nil {
+		return "", fmt.Errorf("get server socket: %w", err)
+	}
+	sessionName, err := mgr.SessionName()
+	if err != nil {
+		return "", fmt.Errorf("get session name: %w", err)
+	}
+	return pathFor(serverSocket, sessionName)
+}
+
+// publishSocket records a socket path in a session option so clients can
+// find it
+func publishSocket(mgr *tmux.Manager, option, path string) {
+	if err := mgr.SetSessionOption(option, path); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: publish %s: %v\n", option, err)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xunzhou/muxctl/internal"
+	"github.com/xunzhou/muxctl/pkg/config"
+	"github.com/xunzhou/muxctl/pkg/record"
+)
+
+// recordPipeCommand is the hidden subcommand pipe-pane runs for recordings;
+// see tmux.WithRecordCommand for its arguments
+const recordPipeCommand = "record-pipe"
+
+// runLocalSubcommand runs subcommands that work on files rather than a
+// running muxctl; ok is false for any other subcommand
+func runLocalSubcommand(args []string) (code int, ok bool) {
+	switch args[0] {
+	case recordPipeCommand:
+		return runRecordPipe(args[1:]), true
+	case "replay":
+		return runReplay(args[1:]), true
+	case "export":
+		return runExport(args[1:]), true
+	case "redact":
+		return runRedact(args[1:]), true
+	}
+	return 0, false
+}
+
+// runRecordPipe records stdin, the output pipe-pane forwards, into a log
+// with secrets redacted
+// Arguments: [-config file] <log> <resource> <width> <height>
+func runRecordPipe(args []string) int {
+	fs := flag.NewFlagSet("muxctl "+recordPipeCommand, flag.ContinueOnError)
+	configPath := fs.String("config", "", "config file with redaction rules")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	args = fs.Args()
+	if len(args) != 4 {
+		fmt.Fprintf(os.Stderr, "Error: %s takes 4 arguments, got %d\n", recordPipeCommand, len(args))
+		return 2
+	}
+	width, _ := strconv.Atoi(args[2])
+	height, _ := strconv.Atoi(args[3])
+
+	// Nothing is recorded rather than recording secrets
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+	redactor, err := redactorFromConfig(cfg.Redaction)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	f, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+
+	header := record.Header{Resource: args[1], Width: width, Height: height}
+	if err := record.Record(os.Stdin, f, header, redactor.Redact); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runReplay plays a recording log or asciinema cast in the terminal
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("muxctl replay", flag.ContinueOnError)
+	speed := fs.Float64("speed", 1, "playback speed multiplier")
+	fs.Usage = func() { fmt.Fprint(fs.Output(), subcommandUsage) }
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Error: replay takes 1 argument, got %d\n\n%s", fs.NArg(), subcommandUsage)
+		return 2
+	}
+
+	rec, err := record.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// The recorded escape sequences drive the terminal, so no renderer
+	p := tea.NewProgram(internal.NewReplay(rec, os.Stdout, *speed), tea.WithAltScreen(), tea.WithoutRenderer())
+	if _, err := p.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runExport converts a recording log into an asciinema v2 cast
+// The cast is written next to the log unless an output path is given
+func runExport(args []string) int {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Fprintf(os.Stderr, "Error: export takes 1 or 2 arguments, got %d\n\n%s", len(args), subcommandUsage)
+		return 2
+	}
+	rec, err := record.Load(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	out := strings.TrimSuffix(args[0], ".log") + ".cast"
+	if len(args) == 2 {
+		out = args[1]
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := record.WriteCast(f, rec); err != nil {
+		f.Close()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := f.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Println(out)
+	return 0
+}
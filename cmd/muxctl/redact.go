@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/xunzhou/muxctl/pkg/config"
+)
+
+// runRedact previews what the configured redaction rules replace in a file
+// or stdin, or with -apply prints the redacted text
+func runRedact(args []string) int {
+	fs := flag.NewFlagSet("muxctl redact", flag.ContinueOnError)
+	configPath := fs.String("config", "", "config file with redaction rules")
+	apply := fs.Bool("apply", false, "print the redacted text instead of the matches")
+	fs.Usage = func() { fmt.Fprint(fs.Output(), subcommandUsage) }
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintf(os.Stderr, "Error: redact takes at most 1 argument, got %d\n\n%s", fs.NArg(), subcommandUsage)
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+	redactor, err := redactorFromConfig(cfg.Redaction)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	in := io.Reader(os.Stdin)
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+	data, err := io.ReadAll(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	text := string(data)
+
+	if *apply {
+		fmt.Print(redactor.Redact(text))
+		return 0
+	}
+
+	matches := redactor.Find(text)
+	if len(matches) == 0 {
+		fmt.Println("No secrets found")
+		return 0
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LINE\tRULE\tMATCH")
+	for _, m := range matches {
+		line := strings.Count(text[:m.Start], "\n") + 1
+		fmt.Fprintf(w, "%d\t%s\t%s\n", line, m.Rule, previewMatch(m.Text))
+	}
+	w.Flush()
+	return 0
+}
+
+// previewMatch shortens a match to one line for the preview table
+func previewMatch(s string) string {
+	const max = 60
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i] + " ..."
+	}
+	if len(s) > max {
+		s = s[:max-3] + "..."
+	}
+	return s
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xunzhou/muxctl/pkg/tmux"
+)
+
+// The TUI list shows resources first and open AI chats after them; a
+// selectedIdx past the last resource points at an AI chat
+
+// listLen returns the number of rows in the TUI list
+func (m *Model) listLen() int {
+	return len(m.resources) + len(m.tmux.GetAIChats())
+}
+
+// selectedAIChat returns the AI chat under the cursor, if any
+func (m *Model) selectedAIChat() (tmux.AIChat, bool) {
+	i := m.selectedIdx - len(m.resources)
+	chats := m.tmux.GetAIChats()
+	if i < 0 || i >= len(chats) {
+		return tmux.AIChat{}, false
+	}
+	return chats[i], true
+}
+
+// clampSelection keeps the cursor on a row after AI chats close
+func (m *Model) clampSelection() {
+	if n := m.listLen(); m.selectedIdx >= n {
+		m.selectedIdx = n - 1
+	}
+	if m.selectedIdx < 0 {
+		m.selectedIdx = 0
+	}
+}
+
+// aiChatLabel returns how an AI chat is listed, e.g. "oom triage (ai-3)"
+func aiChatLabel(chat tmux.AIChat) string {
+	if chat.Title == "" {
+		return chat.ID
+	}
+	return fmt.Sprintf("%s (%s)", chat.Title, chat.ID)
+}
+
+// attachAIChat swaps an open AI chat into the bottom pane
+func (m *Model) attachAIChat(aiChatID string) {
+	if err := m.tmux.AttachExistingAIChat(aiChatID); err != nil {
+		m.message = fmt.Sprintf("Error: %v", err)
+		return
+	}
+	m.activeResourceID = m.tmux.GetActiveResource()
+	m.message = fmt.Sprintf("Activated: %s", aiChatID)
+}
+
+// closeAIChat closes an AI chat pane
+func (m *Model) closeAIChat(chat tmux.AIChat) {
+	if err := m.tmux.CloseAIChat(chat.ID); err != nil {
+		m.message = fmt.Sprintf("Error closing: %v", err)
+		return
+	}
+	m.message = fmt.Sprintf("Closed: %s", chat.ID)
+	m.clampSelection()
+}
+
+// startRename opens the title prompt for an AI chat
+func (m *Model) startRename(chat tmux.AIChat) {
+	m.renaming = chat.ID
+	m.renameInput = chat.Title
+	m.message = ""
+}
+
+// updateRename handles a key press while the title prompt is open
+func (m *Model) updateRename(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.renaming = ""
+
+	case "enter":
+		aiChatID := m.renaming
+		m.renaming = ""
+		if err := m.tmux.RenameAIChat(aiChatID, m.renameInput); err != nil {
+			m.message = fmt.Sprintf("Error renaming: %v", err)
+		} else {
+			m.message = fmt.Sprintf("Renamed: %s", aiChatID)
+		}
+
+	case "backspace":
+		if runes := []rune(m.renameInput); len(runes) > 0 {
+			m.renameInput = string(runes[:len(runes)-1])
+		}
+
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			m.renameInput += string(msg.Runes)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/xunzhou/muxctl/pkg/config"
+	"github.com/xunzhou/muxctl/pkg/tmux"
+)
+
+// contextData is what the context template is executed with
+type contextData struct {
+	ID        string
+	Name      string
+	Group     string
+	Command   string
+	Lines     int  // Lines of captured output
+	Selection bool // Content is the copy-mode selection rather than the last lines
+	Content   string
+}
+
+// contextResource returns the resource whose terminal the send-context
+// action captures: the selected one if its pane is open, else the one shown
+func (m *Model) contextResource() (config.Resource, string, bool) {
+	panes := m.tmux.GetResourcePanes()
+	if m.selectedIdx < len(m.resources) {
+		res := m.resources[m.selectedIdx]
+		if paneID, ok := panes[res.ID]; ok {
+			return res, paneID, true
+		}
+	}
+
+	active := m.tmux.GetActiveResource()
+	paneID, ok := panes[active]
+	if !ok {
+		return config.Resource{}, "", false
+	}
+	for _, res := range m.resources {
+		if res.ID == active {
+			return res, paneID, true
+		}
+	}
+	return config.Resource{ID: active}, paneID, true
+}
+
+// captureContext captures a resource pane's selection or last lines and
+// wraps them in the context template
+// Captures come back from the manager with secrets already redacted
+func (m *Model) captureContext(res config.Resource, paneID string) (string, error) {
+	content, selection, err := m.tmux.CaptureSelection(paneID)
+	if err != nil {
+		return "", err
+	}
+	if !selection {
+		capture, err := m.tmux.CapturePane(paneID, tmux.CaptureOptions{Lines: m.contextLines, Join: true})
+		if err != nil {
+			return "", err
+		}
+		content = capture.Content
+	}
+	content = strings.TrimRight(content, "\n ")
+	if content == "" {
+		return "", fmt.Errorf("%s has no output to send", res.ID)
+	}
+
+	data := contextData{
+		ID:        res.ID,
+		Name:      res.DisplayName(),
+		Group:     res.Group,
+		Command:   res.Command,
+		Lines:     strings.Count(content, "\n") + 1,
+		Selection: selection,
+		Content:   content,
+	}
+	var b strings.Builder
+	if err := m.contextTemplate.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("context template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// sendContext captures the context resource's terminal and sends it to an
+// AI chat: the only open one, one picked from several, or a new chat
+func (m *Model) sendContext() {
+	res, paneID, ok := m.contextResource()
+	if !ok {
+		m.message = "No open resource terminal to send"
+		return
+	}
+	text, err := m.captureContext(res, paneID)
+	if err != nil {
+		m.message = fmt.Sprintf("Error: %v", err)
+		return
+	}
+
+	chats := m.tmux.GetAIChats()
+	switch len(chats) {
+	case 0:
+		// The context is pasted into the new chat like into any other
+		// rather than passed on its command line
+		aiChatID, err := m.tmux.StartAIChat(tmux.AIChatOptions{})
+		if err != nil {
+			m.message = fmt.Sprintf("Error launching AI chat: %v", err)
+			return
+		}
+		m.pasteContext(aiChatID, res.ID, text)
+	case 1:
+		m.pasteContext(chats[0].ID, res.ID, text)
+	default:
+		m.picker = newPicker(m.tmux, nil)
+		m.picker.prompt = fmt.Sprintf("Send %s to", res.ID)
+		m.picker.filter = filterAI
+		m.picker.refresh()
+		m.picker.action = func(item pickerItem) {
+			m.pasteContext(item.id, res.ID, text)
+		}
+		m.picker.refreshPreview(m.tmux)
+		m.message = ""
+	}
+}
+
+// pasteContext pastes context into an AI chat and shows the chat, leaving
+// the user to add a question and submit it
+func (m *Model) pasteContext(aiChatID, resourceID, text string) {
+	paneID, err := m.tmux.PaneFor(aiChatID)
+	if err == nil {
+		err = m.tmux.PasteToPane(paneID, text)
+	}
+	if err == nil {
+		err = m.tmux.AttachExistingAIChat(aiChatID)
+	}
+	if err != nil {
+		m.message = fmt.Sprintf("Error: %v", err)
+		return
+	}
+	m.activeResourceID = m.tmux.GetActiveResource()
+	m.message = fmt.Sprintf("Sent context from %s to %s", resourceID, aiChatID)
+}
+
+// parseContextTemplate parses the configured context template
+func parseContextTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = config.DefaultContextTemplate
+	}
+	tmpl, err := template.New("context").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("context template: %w", err)
+	}
+	return tmpl, nil
+}
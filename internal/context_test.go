@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xunzhou/muxctl/pkg/tmux"
+)
+
+func TestContextResource(t *testing.T) {
+	m, _ := newTestModel(t, nil)
+	if _, _, ok := m.contextResource(); ok {
+		t.Fatalf("contextResource found a resource with no terminal open")
+	}
+
+	attach := func(id string) string {
+		t.Helper()
+		if err := m.tmux.AttachResourceTerminal(id); err != nil {
+			t.Fatalf("AttachResourceTerminal %s: %v", id, err)
+		}
+		return m.tmux.GetResourcePanes()[id]
+	}
+	dbPane := attach("db-main")
+	podPane := attach("pod-a")
+	m.tmux.SetResourceSpec("adhoc", tmux.ResourceSpec{})
+	adhocPane := attach("adhoc")
+
+	tests := []struct {
+		name     string
+		selected int
+		id       string
+		display  string
+		pane     string
+	}{
+		// The selected resource wins over the one on screen
+		{"selected", 1, "db-main", "Database", dbPane},
+		{"selected first", 0, "pod-a", "pod-a", podPane},
+		// With an AI chat row selected the resource on screen is sent, even
+		// one the list does not know
+		{"shown", 2, "adhoc", "adhoc", adhocPane},
+	}
+	for _, tt := range tests {
+		m.selectedIdx = tt.selected
+		res, pane, ok := m.contextResource()
+		if !ok || res.ID != tt.id || res.DisplayName() != tt.display || pane != tt.pane {
+			t.Errorf("%s: contextResource = %+v, %s, %v, want %s (%s) in %s", tt.name, res, pane, ok, tt.id, tt.display, tt.pane)
+		}
+	}
+}
+
+func TestCaptureContext(t *testing.T) {
+	m, srv := newTestModel(t, nil)
+	tmpl, err := parseContextTemplate("{{.Name}} {{.Lines}} {{.Selection}}\n{{.Content}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.contextTemplate = tmpl
+
+	for _, id := range []string{"pod-a", "db-main"} {
+		if err := m.tmux.AttachResourceTerminal(id); err != nil {
+			t.Fatalf("AttachResourceTerminal %s: %v", id, err)
+		}
+	}
+	res := m.resources[1]
+	pane := m.tmux.GetResourcePanes()["db-main"]
+
+	// Output up to the cursor is captured, without its trailing blank lines
+	srv.SetPaneContent(pane, "$ make\nok\n\n")
+	srv.SetCursor(pane, 0, 2)
+	if got, err := m.captureContext(res, pane); err != nil || got != "Database 2 false\n$ make\nok" {
+		t.Errorf("captureContext = %q, %v, want the last lines", got, err)
+	}
+
+	// ... but a copy-mode selection is sent instead
+	srv.SetSelection(pane, "ok")
+	if got, err := m.captureContext(res, pane); err != nil || got != "Database 1 true\nok" {
+		t.Errorf("captureContext = %q, %v, want the selection", got, err)
+	}
+
+	empty := m.tmux.GetResourcePanes()["pod-a"]
+	srv.SetPaneContent(empty, "\n  \n")
+	if _, err := m.captureContext(m.resources[0], empty); err == nil || !strings.Contains(err.Error(), "no output") {
+		t.Errorf("captureContext of a blank pane = %v, want a no output error", err)
+	}
+}
+
+func TestSendContextStartsChat(t *testing.T) {
+	m, srv := newTestModel(t, nil)
+	if err := m.tmux.AttachResourceTerminal("db-main"); err != nil {
+		t.Fatalf("AttachResourceTerminal: %v", err)
+	}
+	srv.SetPaneContent(m.tmux.GetResourcePanes()["db-main"], "ERROR: disk full")
+	m.selectedIdx = 1
+	want, err := m.captureContext(m.resources[1], m.tmux.GetResourcePanes()["db-main"])
+	if err != nil {
+		t.Fatalf("captureContext: %v", err)
+	}
+
+	press(m, "c")
+
+	chats := m.tmux.GetAIChats()
+	if len(chats) != 1 {
+		t.Fatalf("AI chats = %+v, want a new one", chats)
+	}
+	pane := chats[0].PaneID
+	// The chat starts without a prompt and is pasted the context
+	if command := srv.PaneCommand(pane); strings.Contains(command, "disk full") {
+		t.Errorf("the context was passed on the chat's command line %q", command)
+	}
+	if got := srv.SentKeys(pane); !reflect.DeepEqual(got, []string{want}) {
+		t.Errorf("pasted %q, want %q", got, want)
+	}
+	if m.tmux.GetBottomPane() != pane {
+		t.Errorf("the new chat is not on screen")
+	}
+}
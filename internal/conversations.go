@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xunzhou/muxctl/pkg/ai"
+	"github.com/xunzhou/muxctl/pkg/tmux"
+)
+
+// conversationMsg carries a socket request into the model
+// The manager is only driven from Update, so the socket server hands
+// requests over and waits for the reply instead of calling tmux itself
+type conversationMsg struct {
+	req   *ai.ConversationRequest
+	reply chan *ai.ConversationResponse
+}
+
+// HandleConversation implements ai.ConversationHandler
+func (m *Model) HandleConversation(req *ai.ConversationRequest) *ai.ConversationResponse {
+	reply := make(chan *ai.ConversationResponse, 1)
+	select {
+	case m.conversations <- conversationMsg{req: req, reply: reply}:
+	case <-m.done:
+		return &ai.ConversationResponse{ConversationID: req.ConversationID, Error: errClosed}
+	}
+	select {
+	case resp := <-reply:
+		return resp
+	case <-m.done:
+		return &ai.ConversationResponse{ConversationID: req.ConversationID, Error: errClosed}
+	}
+}
+
+// waitForConversation blocks until the socket server receives a request
+func waitForConversation(ch <-chan conversationMsg) tea.Cmd {
+	return func() tea.Msg {
+		return <-ch
+	}
+}
+
+// handleConversation carries out a socket request on the tmux layout
+func (m *Model) handleConversation(req *ai.ConversationRequest) *ai.ConversationResponse {
+	resp := &ai.ConversationResponse{ConversationID: req.ConversationID}
+
+	var err error
+	switch req.Action {
+	case ai.ConvActionStart:
+		resp.ConversationID, err = m.tmux.StartAIChat(tmux.AIChatOptions{Prompt: req.Context.Prompt()})
+		if err == nil {
+			m.activeResourceID = m.tmux.GetActiveResource()
+			m.message = fmt.Sprintf("Started %s for alert %s", resp.ConversationID, req.Context.AlertFingerprint)
+			if req.Options.ExpandWidth > 0 {
+				err = m.tmux.ResizeAIChat(resp.ConversationID, req.Options.ExpandWidth)
+			}
+		}
+
+	case ai.ConvActionSend:
+		err = m.tmux.SendToAIChat(req.ConversationID, req.Message)
+
+	case ai.ConvActionEnd:
+		err = m.tmux.CloseAIChat(req.ConversationID)
+		if err == nil {
+			m.message = fmt.Sprintf("Closed: %s", req.ConversationID)
+		}
+
+	case ai.ConvActionResize:
+		err = m.tmux.ResizeAIChat(req.ConversationID, req.Options.ExpandWidth)
+	}
+
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	resp.Success = true
+	return resp
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xunzhou/muxctl/pkg/ai"
+	"github.com/xunzhou/muxctl/pkg/tmux/tmuxtest"
+)
+
+// resizes returns the resize-pane calls made on the fake server
+func resizes(srv *tmuxtest.Server) [][]string {
+	var calls [][]string
+	for _, call := range srv.Calls() {
+		if len(call) > 0 && call[0] == "resize-pane" {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func TestHandleConversation(t *testing.T) {
+	m, srv := newTestModel(t, nil)
+
+	resp := m.handleConversation(&ai.ConversationRequest{
+		Action:  ai.ConvActionStart,
+		Context: ai.ConversationRequestContext{AlertFingerprint: "a1b2", Cluster: "prod"},
+		Options: ai.ConversationOptions{ExpandWidth: 70},
+	})
+	if !resp.Success || resp.ConversationID != "ai-1" {
+		t.Fatalf("start = %+v, want ai-1", resp)
+	}
+	pane := m.tmux.GetAIPanes()["ai-1"]
+	if pane != m.tmux.GetBottomPane() {
+		t.Fatalf("ai-1's pane %s is not on screen", pane)
+	}
+	// Alone below the full-width TUI the chat can only grow taller
+	want := [][]string{{"resize-pane", "-t", pane, "-y", "70%"}}
+	if got := resizes(srv); !reflect.DeepEqual(got, want) {
+		t.Errorf("start resized %q, want %q", got, want)
+	}
+
+	resp = m.handleConversation(&ai.ConversationRequest{Action: ai.ConvActionSend, ConversationID: "ai-1", Message: "why Enter?"})
+	if !resp.Success {
+		t.Fatalf("send = %+v", resp)
+	}
+	if got := srv.SentKeys(pane); !reflect.DeepEqual(got, []string{"why Enter?", "Enter"}) {
+		t.Errorf("sent keys = %q, want the literal message then Enter", got)
+	}
+
+	// In the dual-slot layout the chat shares its row with the resource slot
+	if _, err := m.tmux.ToggleDualSlot(); err != nil {
+		t.Fatalf("ToggleDualSlot: %v", err)
+	}
+	resp = m.handleConversation(&ai.ConversationRequest{Action: ai.ConvActionResize, ConversationID: "ai-1", Options: ai.ConversationOptions{ExpandWidth: 40}})
+	if !resp.Success {
+		t.Fatalf("resize = %+v", resp)
+	}
+	if got := resizes(srv); got[len(got)-1][3] != "-x" {
+		t.Errorf("dual-slot resize = %q, want -x", got[len(got)-1])
+	}
+
+	for _, tt := range []struct {
+		name string
+		req  ai.ConversationRequest
+		want string
+	}{
+		{"unknown chat", ai.ConversationRequest{Action: ai.ConvActionSend, ConversationID: "ai-9", Message: "hi"}, "does not exist"},
+		{"width out of range", ai.ConversationRequest{Action: ai.ConvActionResize, ConversationID: "ai-1", Options: ai.ConversationOptions{ExpandWidth: 120}}, "out of range"},
+	} {
+		if resp := m.handleConversation(&tt.req); resp.Success || !strings.Contains(resp.Error, tt.want) {
+			t.Errorf("%s: response %+v, want an error containing %q", tt.name, resp, tt.want)
+		}
+	}
+
+	// A second chat stashes the first, which then has no width to set
+	resp = m.handleConversation(&ai.ConversationRequest{Action: ai.ConvActionStart, Context: ai.ConversationRequestContext{AlertFingerprint: "c3d4"}})
+	if !resp.Success || resp.ConversationID != "ai-2" {
+		t.Fatalf("second start = %+v, want ai-2", resp)
+	}
+	calls := len(resizes(srv))
+	resp = m.handleConversation(&ai.ConversationRequest{Action: ai.ConvActionResize, ConversationID: "ai-1", Options: ai.ConversationOptions{ExpandWidth: 60}})
+	if resp.Success || !strings.Contains(resp.Error, "not on screen") {
+		t.Errorf("resizing a stashed chat = %+v, want an error", resp)
+	}
+	if len(resizes(srv)) != calls {
+		t.Errorf("resizing a stashed chat ran resize-pane")
+	}
+
+	resp = m.handleConversation(&ai.ConversationRequest{Action: ai.ConvActionEnd, ConversationID: "ai-2"})
+	if !resp.Success {
+		t.Fatalf("end = %+v", resp)
+	}
+	if _, ok := m.tmux.GetAIPanes()["ai-2"]; ok {
+		t.Errorf("ai-2 is still tracked after end")
+	}
+	if srv.PaneWindow(m.tmux.GetAISlotPane()) == "" {
+		t.Errorf("ending the chat on screen left its slot empty")
+	}
+}
@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xunzhou/muxctl/pkg/ctl"
+	"github.com/xunzhou/muxctl/pkg/tmux"
+)
+
+// ctlMsg carries a request from a muxctl subcommand into the model
+// Like conversationMsg, it is answered from Update so only the Bubble Tea
+// loop drives the manager
+type ctlMsg struct {
+	req   *ctl.Request
+	reply chan *ctl.Response
+}
+
+// HandleCtl implements ctl.Handler
+func (m *Model) HandleCtl(req *ctl.Request) *ctl.Response {
+	reply := make(chan *ctl.Response, 1)
+	select {
+	case m.ctlRequests <- ctlMsg{req: req, reply: reply}:
+	case <-m.done:
+		return &ctl.Response{ID: req.ID, Error: errClosed}
+	}
+	select {
+	case resp := <-reply:
+		return resp
+	case <-m.done:
+		return &ctl.Response{ID: req.ID, Error: errClosed}
+	}
+}
+
+// waitForCtl blocks until a subcommand sends a request
+func waitForCtl(ch <-chan ctlMsg) tea.Cmd {
+	return func() tea.Msg {
+		return <-ch
+	}
+}
+
+// handleCtl carries out a subcommand request on the tmux layout
+func (m *Model) handleCtl(req *ctl.Request) *ctl.Response {
+	// Pane IDs such as "%5", e.g. from a status bar click, name whatever the
+	// pane currently shows
+	if strings.HasPrefix(req.ID, "%") {
+		id, ok := m.tmux.IDForPane(req.ID)
+		if !ok {
+			return &ctl.Response{ID: req.ID, Error: fmt.Sprintf("no resource or AI chat in pane %s", req.ID)}
+		}
+		req.ID = id
+	}
+
+	resp := &ctl.Response{ID: req.ID}
+	_, isAI := m.tmux.GetAIPanes()[req.ID]
+
+	var err error
+	switch req.Action {
+	case ctl.ActionList:
+		resp.Items = m.listItems()
+
+	case ctl.ActionAttach:
+		if isAI {
+			err = m.tmux.AttachExistingAIChat(req.ID)
+		} else if !m.knownResource(req.ID) {
+			err = fmt.Errorf("unknown resource or AI chat %q", req.ID)
+		} else {
+			err = m.tmux.AttachResourceTerminal(req.ID)
+		}
+		if err == nil {
+			m.message = fmt.Sprintf("Activated: %s", req.ID)
+		}
+
+	case ctl.ActionAINew:
+		resp.ID, err = m.tmux.StartAIChat(tmux.AIChatOptions{Backend: req.Backend, Resume: req.Resume})
+		if err == nil {
+			m.message = "Launched new AI chat"
+		}
+
+	case ctl.ActionClose:
+		if isAI {
+			err = m.tmux.CloseAIChat(req.ID)
+		} else {
+			err = m.tmux.CloseResourcePane(req.ID)
+		}
+		if err == nil {
+			m.message = fmt.Sprintf("Closed: %s", req.ID)
+		}
+
+	case ctl.ActionRename:
+		err = m.tmux.RenameAIChat(req.ID, req.Title)
+		if err == nil {
+			m.message = fmt.Sprintf("Renamed: %s", req.ID)
+		}
+
+	case ctl.ActionSend:
+		var paneID string
+		if paneID, err = m.tmux.PaneFor(req.ID); err == nil {
+			err = m.tmux.SendKeys(paneID, req.Keys...)
+		}
+
+	case ctl.ActionCapture:
+		var paneID string
+		if paneID, err = m.tmux.PaneFor(req.ID); err == nil {
+			var capture *tmux.Capture
+			// Joined lines keep a secret wrapped across rows in one piece for
+			// the capture filter
+			if capture, err = m.tmux.CapturePane(paneID, tmux.CaptureOptions{Join: true}); err == nil {
+				resp.Content = capture.Content
+			}
+		}
+	}
+
+	// The layout may have changed outside the TUI's own key handling
+	m.activeResourceID = m.tmux.GetActiveResource()
+
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	resp.Success = true
+	return resp
+}
+
+// knownResource reports whether a resource is configured, discovered or
+// still has an open pane
+func (m *Model) knownResource(id string) bool {
+	if _, ok := m.tmux.GetResourcePanes()[id]; ok {
+		return true
+	}
+	for _, res := range m.resources {
+		if res.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// listItems describes every known resource followed by the open AI chats
+func (m *Model) listItems() []ctl.Item {
+	resourcePanes := m.tmux.GetResourcePanes()
+	activeAIChat := m.tmux.GetActiveAIChat()
+
+	var items []ctl.Item
+	for _, res := range m.resources {
+		items = append(items, ctl.Item{
+			Kind:   ctl.KindResource,
+			ID:     res.ID,
+			Name:   res.DisplayName(),
+			Group:  res.Group,
+			PaneID: resourcePanes[res.ID],
+			Active: res.ID == m.activeResourceID,
+		})
+	}
+
+	for _, chat := range m.tmux.GetAIChats() {
+		items = append(items, ctl.Item{
+			Kind:    ctl.KindAI,
+			ID:      chat.ID,
+			Name:    chat.Title,
+			PaneID:  chat.PaneID,
+			Active:  chat.ID == activeAIChat,
+			Backend: chat.Backend,
+			Session: chat.Session,
+		})
+	}
+	return items
+}
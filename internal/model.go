@@ -3,60 +3,253 @@ package internal
 import (
 	"fmt"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
-	"github.com/xunzhou/muxctl/pkg/tmux"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xunzhou/muxctl/pkg/config"
+	"github.com/xunzhou/muxctl/pkg/provider"
+	"github.com/xunzhou/muxctl/pkg/tmux"
 )
 
 // Model is the Bubble Tea model for the terminal multiplexer
 type Model struct {
 	tmux             *tmux.Manager
-	resources        []string
+	resources        []config.Resource // Static resources followed by discovered ones
+	static           []config.Resource // Resources from the config file
+	providers        []providerState   // Dynamic resource sources
 	selectedIdx      int
 	activeResourceID string
 	message          string
 	quitting         bool
+	confirmQuit      bool // Waiting for y/n after q
+	notifications    <-chan tmux.Notification
+	conversations    chan conversationMsg // Requests from the AI conversation socket
+	ctlRequests      chan ctlMsg          // Requests from muxctl subcommands
+	done             chan struct{}        // Closed by Close once the TUI has exited
+	closeOnce        sync.Once            // Guards closing done
+	picker           *picker              // Open AI/resource picker, if any
+	renaming         string               // AI chat whose title is being edited ("" = none)
+	renameInput      string               // Title typed so far
+	height           int                  // Terminal height from the last WindowSizeMsg
+	recordingDir     string               // Where resource recordings are written
+	contextLines     int                  // Lines the send-context action captures
+	contextTemplate  *template.Template   // Prompt wrapped around sent context
+}
+
+// NewModel creates a new model listing the configured resources and
+// polling the configured resource providers
+func NewModel(tmuxMgr *tmux.Manager, cfg *config.Config) (*Model, error) {
+	m := &Model{
+		tmux:          tmuxMgr,
+		static:        cfg.Resources,
+		selectedIdx:   0,
+		conversations: make(chan conversationMsg),
+		ctlRequests:   make(chan ctlMsg),
+		done:          make(chan struct{}),
+		recordingDir:  cfg.Recording.Dir,
+		contextLines:  cfg.Context.Lines,
+		// A restarted muxctl may have re-adopted the resource on screen
+		activeResourceID: tmuxMgr.GetActiveResource(),
+	}
+
+	tmpl, err := parseContextTemplate(cfg.Context.Template)
+	if err != nil {
+		return nil, err
+	}
+	m.contextTemplate = tmpl
+
+	for _, pc := range cfg.Providers {
+		p, err := provider.New(pc)
+		if err != nil {
+			return nil, err
+		}
+		m.providers = append(m.providers, providerState{provider: p, interval: pc.Interval})
+	}
+
+	backends := make([]tmux.AIBackend, len(cfg.AIBackends))
+	for i, b := range cfg.AIBackends {
+		backends[i] = aiBackend(b)
+	}
+	tmuxMgr.SetAIBackends(backends)
+
+	m.rebuildResources()
+	return m, nil
+}
+
+// errClosed answers socket requests that arrive after the TUI has exited
+const errClosed = "muxctl is exiting"
+
+// Close makes socket requests fail instead of waiting for a TUI that has
+// exited; call it once the Bubble Tea program returns
+func (m *Model) Close() {
+	m.closeOnce.Do(func() { close(m.done) })
+}
+
+// aiBackend converts a configured AI backend for the tmux manager
+func aiBackend(b config.AIBackend) tmux.AIBackend {
+	return tmux.AIBackend{
+		Name:        b.Name,
+		Command:     b.Command,
+		Env:         b.Env,
+		Prefix:      b.Prefix,
+		SessionArgs: b.SessionArgs,
+		ResumeArgs:  b.ResumeArgs,
+		PromptArgs:  b.PromptArgs,
+	}
 }
 
-// NewModel creates a new model
-func NewModel(tmuxMgr *tmux.Manager) *Model {
-	return &Model{
-		tmux: tmuxMgr,
-		resources: []string{
-			"pod-a",
-			"pod-b",
-			"pod-c",
-			"service-x",
-			"service-y",
-		},
-		selectedIdx: 0,
+// resourceSpec converts a configured resource into a terminal spec
+func resourceSpec(res config.Resource) tmux.ResourceSpec {
+	return tmux.ResourceSpec{
+		Dir:     res.Dir,
+		Env:     res.Env,
+		Command: res.Command,
+		Prompt:  res.Prompt,
 	}
 }
 
 func (m *Model) Init() tea.Cmd {
-	return tea.Tick(time.Second*2, func(t time.Time) tea.Msg {
-		return tickMsg(t)
-	})
+	cmds := []tea.Cmd{
+		tea.Tick(time.Second*2, func(t time.Time) tea.Msg {
+			return tickMsg(t)
+		}),
+	}
+
+	// In control mode, tmux reports window changes as they happen
+	notifications, _ := m.tmux.Subscribe()
+	if notifications != nil {
+		m.notifications = notifications
+		cmds = append(cmds, waitForNotification(notifications))
+	}
+
+	// Pane lifecycle events from tmux hooks, if they were started
+	if events := m.tmux.Events(); events != nil {
+		cmds = append(cmds, waitForEvent(events))
+	}
+
+	// Requests from the AI conversation socket, if it is being served
+	cmds = append(cmds, waitForConversation(m.conversations))
+
+	// Requests from muxctl subcommands, if the control socket is being served
+	cmds = append(cmds, waitForCtl(m.ctlRequests))
+
+	// Discover dynamic resources right away; each provider then re-polls on
+	// its own interval
+	for i := range m.providers {
+		cmds = append(cmds, m.pollProvider(i))
+	}
+	return tea.Batch(cmds...)
 }
 
 type tickMsg time.Time
 
+// notificationMsg carries a tmux control-mode notification into the model
+type notificationMsg tmux.Notification
+
+// eventMsg carries a pane lifecycle event into the model
+type eventMsg tmux.Event
+
+// waitForEvent blocks until the next pane lifecycle event
+// It returns a nil message once the channel is closed
+func waitForEvent(ch <-chan tmux.Event) tea.Cmd {
+	return func() tea.Msg {
+		ev, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return eventMsg(ev)
+	}
+}
+
+// waitForNotification blocks until tmux reports a window or pane change
+// Output and other high-volume notifications are skipped here so they never
+// reach Update
+func waitForNotification(ch <-chan tmux.Notification) tea.Cmd {
+	return func() tea.Msg {
+		for n := range ch {
+			switch n.Name {
+			case tmux.NotifyWindowClose, tmux.NotifyUnlinkedWindowClose,
+				tmux.NotifyLayoutChange, tmux.NotifyPaneModeChanged:
+				return notificationMsg(n)
+			}
+		}
+		return nil
+	}
+}
+
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tickMsg:
 		// Periodic cleanup and status bar update
 		m.tmux.UpdateStatusBar()
+		m.clampSelection()
 		return m, tea.Tick(time.Second*2, func(t time.Time) tea.Msg {
 			return tickMsg(t)
 		})
 
+	case notificationMsg:
+		// A window or pane changed; refresh immediately instead of on the next tick
+		m.tmux.UpdateStatusBar()
+		return m, waitForNotification(m.notifications)
+
+	case providerMsg:
+		return m, m.handleProviderMsg(msg)
+
+	case providerTickMsg:
+		return m, m.pollProvider(int(msg))
+
+	case conversationMsg:
+		msg.reply <- m.handleConversation(msg.req)
+		return m, waitForConversation(m.conversations)
+
+	case ctlMsg:
+		msg.reply <- m.handleCtl(msg.req)
+		return m, waitForCtl(m.ctlRequests)
+
+	case eventMsg:
+		// A pane exited or was killed; drop it from tracking right away so
+		// the ●/○ markers and status bar never show stale panes
+		m.tmux.UpdateStatusBar()
+		m.clampSelection()
+		if m.activeResourceID != "" && m.tmux.GetActiveResource() == "" {
+			m.message = fmt.Sprintf("%s exited", m.activeResourceID)
+			m.activeResourceID = ""
+		}
+		// Keep listening while events are on; waitForEvent returns nil once
+		// StopEvents closes the channel, which ends the loop
+		if events := m.tmux.Events(); events != nil {
+			return m, waitForEvent(events)
+		}
+		return m, nil
+
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+
 	case tea.KeyMsg:
+		// The picker overlay and title prompt take all keys while open
+		if m.picker != nil {
+			return m, m.updatePicker(msg)
+		}
+		if m.renaming != "" {
+			return m, m.updateRename(msg)
+		}
+		if m.confirmQuit {
+			m.confirmQuit = false
+			m.message = ""
+			if msg.String() == "y" || msg.String() == "Y" {
+				m.quitting = true
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
 		switch msg.String() {
 		case "q":
-			// Use tmux confirm-before to ask for confirmation
-			// This will show a prompt at the bottom of the screen
-			tmuxCmd("confirm-before", "-p", "Really quit? (y/n)", "kill-session")
+			// Ask before quitting; what happens to the panes depends on the exit mode
+			m.confirmQuit = true
+			m.message = fmt.Sprintf("Really quit (%s)? (y/n)", m.tmux.GetExitMode())
 			return m, nil
 
 		case "ctrl+c":
@@ -70,13 +263,20 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case "down", "j":
-			if m.selectedIdx < len(m.resources)-1 {
+			if m.selectedIdx < m.listLen()-1 {
 				m.selectedIdx++
 			}
 
 		case "enter":
-			// Activate the selected resource
-			resourceID := m.resources[m.selectedIdx]
+			// Activate the selected AI chat or resource
+			if chat, ok := m.selectedAIChat(); ok {
+				m.attachAIChat(chat.ID)
+				break
+			}
+			if m.selectedIdx >= len(m.resources) {
+				break
+			}
+			resourceID := m.resources[m.selectedIdx].ID
 			if err := m.tmux.AttachResourceTerminal(resourceID); err != nil {
 				m.message = fmt.Sprintf("Error: %v", err)
 			} else {
@@ -85,8 +285,15 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case "x":
-			// Close the selected resource pane
-			resourceID := m.resources[m.selectedIdx]
+			// Close the selected AI chat or resource pane
+			if chat, ok := m.selectedAIChat(); ok {
+				m.closeAIChat(chat)
+				break
+			}
+			if m.selectedIdx >= len(m.resources) {
+				break
+			}
+			resourceID := m.resources[m.selectedIdx].ID
 			if err := m.tmux.CloseResourcePane(resourceID); err != nil {
 				m.message = fmt.Sprintf("Error closing: %v", err)
 			} else {
@@ -102,14 +309,76 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if err := m.tmux.AttachAIChat(); err != nil {
 				m.message = fmt.Sprintf("Error launching AI chat: %v", err)
 			} else {
-				m.activeResourceID = ""
+				m.activeResourceID = m.tmux.GetActiveResource()
 				m.message = "Launched new AI chat"
 			}
 
+		case "s":
+			// Toggle between one slot and side-by-side resource and AI slots
+			dual, err := m.tmux.ToggleDualSlot()
+			m.activeResourceID = m.tmux.GetActiveResource()
+			switch {
+			case err != nil:
+				m.message = fmt.Sprintf("Error: %v", err)
+			case dual:
+				m.message = "Layout: resource | AI chat"
+			default:
+				m.message = "Layout: single pane"
+			}
+
+		case "z":
+			// Show the terminal full-window; prefix+z or Alt+Enter restores it
+			if err := m.tmux.ToggleZoom(); err != nil {
+				m.message = fmt.Sprintf("Error: %v", err)
+			}
+
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			// Jump to the AI chat whose status bar tab shows this number
+			n := int(msg.String()[0] - '0')
+			if chats := m.tmux.GetAIChats(); n <= len(chats) {
+				m.attachAIChat(chats[n-1].ID)
+			} else {
+				m.message = fmt.Sprintf("No AI chat %d", n)
+			}
+
+		case "c":
+			// Send the selected or shown resource's terminal to an AI chat
+			m.sendContext()
+
+		case "R":
+			// Start or stop recording the selected resource
+			if m.selectedIdx < len(m.resources) {
+				m.toggleRecording(m.resources[m.selectedIdx].ID)
+			}
+
+		case "r":
+			// Title the selected AI chat
+			if chat, ok := m.selectedAIChat(); ok {
+				m.startRename(chat)
+			}
+
+		case "b":
+			// Cycle the backend new AI chats use
+			backends := m.tmux.GetAIBackends()
+			current := m.tmux.GetAIBackend()
+			for i, name := range backends {
+				if name == current {
+					next := backends[(i+1)%len(backends)]
+					m.tmux.SelectAIBackend(next)
+					m.message = fmt.Sprintf("AI backend: %s", next)
+					break
+				}
+			}
+
 		case "A":
-			// Show choose-tree for selecting AI chats
-			m.tmux.ShowAIChooser()
-			m.message = "Opening AI chat selector..."
+			// Fuzzy-pick an open AI chat or resource to swap in
+			names := make(map[string]string)
+			for _, res := range m.resources {
+				names[res.ID] = res.DisplayName()
+			}
+			m.picker = newPicker(m.tmux, names)
+			m.picker.refreshPreview(m.tmux)
+			m.message = ""
 		}
 	}
 
@@ -121,6 +390,10 @@ func (m *Model) View() string {
 		return "Goodbye!\n"
 	}
 
+	if m.picker != nil {
+		return m.viewPicker()
+	}
+
 	var b strings.Builder
 
 	b.WriteString("╔═══════════════════════════════════╗\n")
@@ -134,32 +407,73 @@ func (m *Model) View() string {
 		stashedMap[res] = true
 	}
 
+	group := ""
 	for i, res := range m.resources {
+		// Start a new heading whenever the group changes
+		if res.Group != group {
+			group = res.Group
+			b.WriteString(fmt.Sprintf(" %s:\n", group))
+		}
+
 		prefix := "  "
 		if i == m.selectedIdx {
 			prefix = "► "
 		}
 
 		marker := ""
-		if res == m.activeResourceID {
+		if res.ID == m.activeResourceID {
 			marker = " ●"
-		} else if stashedMap[res] {
+		} else if stashedMap[res.ID] {
 			marker = " ○"
 		}
 
-		b.WriteString(fmt.Sprintf("%s%s%s\n", prefix, res, marker))
+		if m.tmux.GetRecording(res.ID) != "" {
+			marker += " ⏺"
+		}
+
+		b.WriteString(fmt.Sprintf("%s%s%s\n", prefix, res.DisplayName(), marker))
+	}
+
+	if chats := m.tmux.GetAIChats(); len(chats) > 0 {
+		b.WriteString("\nAI Chats:\n")
+		activeAIChat := m.tmux.GetActiveAIChat()
+		for i, chat := range chats {
+			prefix := "  "
+			if len(m.resources)+i == m.selectedIdx {
+				prefix = "► "
+			}
+
+			// Every open chat has a pane, so it is either shown or stashed
+			marker := " ○"
+			if chat.ID == activeAIChat {
+				marker = " ●"
+			}
+
+			// Numbered like the status bar tabs, which keys 1-9 select
+			b.WriteString(fmt.Sprintf("%s%d %s%s\n", prefix, i+1, aiChatLabel(chat), marker))
+		}
 	}
 
 	b.WriteString("\nIndicators:\n")
 	b.WriteString("  ●         - Active (visible)\n")
 	b.WriteString("  ○         - Stashed (background)\n")
+	b.WriteString("  ⏺         - Recording\n")
 	b.WriteString("\nKeybindings:\n")
 	b.WriteString("  ↑/k       - Move selection up\n")
 	b.WriteString("  ↓/j       - Move selection down\n")
-	b.WriteString("  ENTER     - Activate resource terminal\n")
-	b.WriteString("  a         - Launch new AI chat\n")
+	b.WriteString("  ENTER     - Activate resource terminal or AI chat\n")
+	b.WriteString("  1-9       - Switch to the Nth AI chat (its tab number)\n")
+	b.WriteString(fmt.Sprintf("  a         - Launch new AI chat (%s)\n", m.tmux.GetAIBackend()))
+	if len(m.tmux.GetAIBackends()) > 1 {
+		b.WriteString("  b         - Switch AI backend\n")
+	}
 	b.WriteString("  A         - Choose AI/Resource (^A=AI ^R=Res ^T=All)\n")
-	b.WriteString("  x         - Close selected resource pane\n")
+	b.WriteString("  s         - Toggle side-by-side resource and AI chat\n")
+	b.WriteString("  z         - Zoom terminal (Alt+Enter to restore)\n")
+	b.WriteString("  c         - Send resource terminal to an AI chat\n")
+	b.WriteString("  r         - Rename selected AI chat\n")
+	b.WriteString("  R         - Start/stop recording selected resource\n")
+	b.WriteString("  x         - Close selected resource pane or AI chat\n")
 	b.WriteString("  Alt+Enter - Focus TUI (from terminal)\n")
 	b.WriteString("  q         - Quit\n\n")
 
@@ -185,7 +499,9 @@ func (m *Model) View() string {
 		b.WriteString("\n")
 	}
 
-	if m.message != "" {
+	if m.renaming != "" {
+		b.WriteString(fmt.Sprintf("\nTitle for %s: %s█  (ENTER=save ESC=cancel)\n", m.renaming, m.renameInput))
+	} else if m.message != "" {
 		b.WriteString(fmt.Sprintf("\n%s\n", m.message))
 	}
 
@@ -193,10 +509,3 @@ func (m *Model) View() string {
 
 	return b.String()
 }
-
-// Helper function for tmux commands (kept for compatibility with existing code)
-func tmuxCmd(args ...string) (string, error) {
-	// This is a stub - the internal model doesn't need to call tmux directly
-	// It should use the Manager methods instead
-	return "", nil
-}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xunzhou/muxctl/pkg/config"
+	"github.com/xunzhou/muxctl/pkg/tmux"
+	"github.com/xunzhou/muxctl/pkg/tmux/tmuxtest"
+)
+
+// newTestModel creates a model for cfg over a fake tmux server with the
+// muxctl layout set up; resources defaults to pod-a and db-main
+func newTestModel(t *testing.T, cfg *config.Config) (*Model, *tmuxtest.Server) {
+	t.Helper()
+	if cfg == nil {
+		cfg = config.Default()
+		cfg.Resources = []config.Resource{{ID: "pod-a"}, {ID: "db-main", Name: "Database"}}
+	}
+
+	srv := tmuxtest.NewServer()
+	mgr, err := tmux.NewManager(tmux.WithRunner(srv))
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if err := mgr.Setup(); err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	m, err := NewModel(mgr, cfg)
+	if err != nil {
+		t.Fatalf("NewModel: %v", err)
+	}
+	t.Cleanup(m.Close)
+	return m, srv
+}
+
+// press sends key presses to the model, one per string; single characters
+// are typed, anything else is a named key such as "ctrl+a"
+func press(m *Model, keys ...string) {
+	named := map[string]tea.KeyType{
+		"enter":     tea.KeyEnter,
+		"esc":       tea.KeyEsc,
+		"up":        tea.KeyUp,
+		"down":      tea.KeyDown,
+		"backspace": tea.KeyBackspace,
+		"ctrl+a":    tea.KeyCtrlA,
+		"ctrl+r":    tea.KeyCtrlR,
+		"ctrl+t":    tea.KeyCtrlT,
+	}
+	for _, key := range keys {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		if t, ok := named[key]; ok {
+			msg = tea.KeyMsg{Type: t}
+		}
+		m.Update(msg)
+	}
+}
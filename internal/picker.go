@@ -0,0 +1,313 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xunzhou/muxctl/pkg/tmux"
+)
+
+// pickerFilter restricts which kinds of items the picker shows
+type pickerFilter int
+
+const (
+	filterAll pickerFilter = iota
+	filterAI
+	filterResources
+)
+
+func (f pickerFilter) String() string {
+	switch f {
+	case filterAI:
+		return "AI chats"
+	case filterResources:
+		return "resources"
+	}
+	return "all"
+}
+
+// Picker item kinds
+const (
+	itemAI       = "ai"
+	itemResource = "res"
+)
+
+// pickerItem is an AI chat or resource with an open pane
+type pickerItem struct {
+	kind   string // itemAI or itemResource
+	id     string // AI chat or resource ID
+	label  string // Text shown and matched against the query
+	paneID string
+}
+
+// picker is an in-process fuzzy finder over open AI chats and resources
+type picker struct {
+	items   []pickerItem
+	visible []pickerItem // Items matching the filter and query, best first
+	query   string
+	filter  pickerFilter
+	cursor  int
+
+	previewPane string // Pane the cached preview belongs to
+	preview     string
+
+	prompt string           // Header shown instead of the filter keys ("" = default)
+	action func(pickerItem) // Run on ENTER instead of switching; locks the filter
+}
+
+// newPicker creates a picker over the manager's open AI chats and resources
+// names maps resource IDs to display names
+func newPicker(mgr *tmux.Manager, names map[string]string) *picker {
+	var items []pickerItem
+	for _, chat := range mgr.GetAIChats() {
+		items = append(items, pickerItem{kind: itemAI, id: chat.ID, label: aiChatLabel(chat), paneID: chat.PaneID})
+	}
+	for resID, paneID := range mgr.GetResourcePanes() {
+		label := resID
+		if name := names[resID]; name != "" && name != resID {
+			label = fmt.Sprintf("%s (%s)", name, resID)
+		}
+		items = append(items, pickerItem{kind: itemResource, id: resID, label: label, paneID: paneID})
+	}
+
+	// AI chats first in creation order, then resources sorted by ID
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].kind != items[j].kind {
+			return items[i].kind == itemAI
+		}
+		return items[i].kind == itemResource && items[i].id < items[j].id
+	})
+
+	p := &picker{items: items}
+	p.refresh()
+	return p
+}
+
+// refresh recomputes the visible items after the query or filter changed
+func (p *picker) refresh() {
+	type scored struct {
+		item  pickerItem
+		score int
+	}
+
+	var matches []scored
+	for _, item := range p.items {
+		if p.filter == filterAI && item.kind != itemAI ||
+			p.filter == filterResources && item.kind != itemResource {
+			continue
+		}
+		score, ok := fuzzyScore(p.query, item.label)
+		if !ok {
+			continue
+		}
+		matches = append(matches, scored{item, score})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	p.visible = p.visible[:0]
+	for _, match := range matches {
+		p.visible = append(p.visible, match.item)
+	}
+	if p.cursor >= len(p.visible) {
+		p.cursor = len(p.visible) - 1
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+}
+
+// selected returns the highlighted item
+func (p *picker) selected() (pickerItem, bool) {
+	if len(p.visible) == 0 {
+		return pickerItem{}, false
+	}
+	return p.visible[p.cursor], true
+}
+
+// refreshPreview captures the highlighted pane if it changed
+func (p *picker) refreshPreview(mgr *tmux.Manager) {
+	item, ok := p.selected()
+	if !ok {
+		p.previewPane, p.preview = "", ""
+		return
+	}
+	if item.paneID == p.previewPane {
+		return
+	}
+
+	capture, err := mgr.CapturePane(item.paneID, tmux.CaptureOptions{})
+	if err != nil {
+		p.previewPane, p.preview = item.paneID, fmt.Sprintf("Error: %v", err)
+		return
+	}
+	p.previewPane, p.preview = item.paneID, capture.Content
+}
+
+// fuzzyScore reports whether query is a case-insensitive subsequence of
+// target and scores the match; consecutive runs and matches at word starts
+// score higher
+func fuzzyScore(query, target string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	t := []rune(strings.ToLower(target))
+
+	score, qi, prev := 0, 0, -2
+	for ti, r := range t {
+		if qi == len(q) {
+			break
+		}
+		if r != q[qi] {
+			continue
+		}
+		score++
+		if ti == prev+1 {
+			score += 2
+		}
+		if ti == 0 || !unicode.IsLetter(t[ti-1]) && !unicode.IsDigit(t[ti-1]) {
+			score += 3
+		}
+		prev = ti
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	return score, true
+}
+
+// updatePicker handles a key press while the picker is open
+func (m *Model) updatePicker(msg tea.KeyMsg) tea.Cmd {
+	p := m.picker
+
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.picker = nil
+		m.message = ""
+		return nil
+
+	case "enter":
+		item, ok := p.selected()
+		m.picker = nil
+		switch {
+		case !ok:
+		case p.action != nil:
+			p.action(item)
+		default:
+			m.activateItem(item)
+		}
+		return nil
+
+	case "up", "ctrl+p", "ctrl+k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+
+	case "down", "ctrl+n", "ctrl+j":
+		if p.cursor < len(p.visible)-1 {
+			p.cursor++
+		}
+
+	case "ctrl+a", "ctrl+r", "ctrl+t":
+		if p.action == nil {
+			p.filter = map[string]pickerFilter{
+				"ctrl+a": filterAI,
+				"ctrl+r": filterResources,
+				"ctrl+t": filterAll,
+			}[msg.String()]
+			p.refresh()
+		}
+
+	case "backspace":
+		if runes := []rune(p.query); len(runes) > 0 {
+			p.query = string(runes[:len(runes)-1])
+			p.refresh()
+		}
+
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			p.query += string(msg.Runes)
+			p.refresh()
+		}
+	}
+
+	p.refreshPreview(m.tmux)
+	return nil
+}
+
+// activateItem swaps the chosen AI chat or resource into the bottom pane
+func (m *Model) activateItem(item pickerItem) {
+	var err error
+	if item.kind == itemAI {
+		err = m.tmux.AttachExistingAIChat(item.id)
+	} else {
+		err = m.tmux.AttachResourceTerminal(item.id)
+	}
+	if err != nil {
+		m.message = fmt.Sprintf("Error: %v", err)
+		return
+	}
+
+	m.activeResourceID = m.tmux.GetActiveResource()
+	m.message = fmt.Sprintf("Activated: %s", item.id)
+}
+
+// viewPicker renders the picker overlay with a preview of the highlighted pane
+func (m *Model) viewPicker() string {
+	p := m.picker
+	var b strings.Builder
+
+	prompt, verb := "Select (^A=AI ^R=Res ^T=All)", "switch"
+	if p.prompt != "" {
+		prompt = p.prompt
+	}
+	if p.action != nil {
+		verb = "send"
+	}
+	b.WriteString(fmt.Sprintf("%s: %s█\n", prompt, p.query))
+	b.WriteString(fmt.Sprintf("AI Chats & Resources [%s]\n\n", p.filter))
+
+	if len(p.visible) == 0 {
+		b.WriteString("  (no matches)\n")
+	}
+	for i, item := range p.visible {
+		prefix := "  "
+		if i == p.cursor {
+			prefix = "► "
+		}
+		b.WriteString(fmt.Sprintf("%s%-3s %s\n", prefix, item.kind, item.label))
+	}
+
+	if item, ok := p.selected(); ok {
+		b.WriteString(fmt.Sprintf("\n── preview: %s ──\n", item.id))
+		b.WriteString(previewTail(p.preview, m.previewLines()))
+		b.WriteString("\n")
+	}
+
+	b.WriteString(fmt.Sprintf("\nENTER=%s  ESC=cancel\n", verb))
+	return b.String()
+}
+
+// previewLines returns how many preview lines fit below the picker list
+func (m *Model) previewLines() int {
+	if m.height == 0 {
+		return 10
+	}
+	lines := m.height - len(m.picker.visible) - 8
+	if lines < 3 {
+		lines = 3
+	}
+	return lines
+}
+
+// previewTail returns the last n non-blank-trailing lines of a capture
+func previewTail(content string, n int) string {
+	lines := strings.Split(strings.TrimRight(content, "\n "), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
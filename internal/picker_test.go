@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		query, target string
+		ok            bool
+	}{
+		{"", "anything", true},
+		{"pa", "pod-a", true},
+		{"PA", "pod-a", true},
+		{"ap", "pod-a", false},
+		{"xyz", "pod-a", false},
+		{"pod-a!", "pod-a", false},
+	}
+	for _, tt := range tests {
+		if _, ok := fuzzyScore(tt.query, tt.target); ok != tt.ok {
+			t.Errorf("fuzzyScore(%q, %q) matched = %v, want %v", tt.query, tt.target, ok, tt.ok)
+		}
+	}
+
+	// Better matches score higher
+	better := []struct {
+		query, better, worse string
+	}{
+		{"db", "db-main", "mongodb"},        // Word start over mid-word
+		{"main", "db-main", "db migration"}, // One run over scattered letters
+		{"pod", "xpod", "xpxoxd"},           // Consecutive over scattered
+	}
+	for _, tt := range better {
+		b, _ := fuzzyScore(tt.query, tt.better)
+		w, _ := fuzzyScore(tt.query, tt.worse)
+		if b <= w {
+			t.Errorf("fuzzyScore(%q): %q scores %d, not above %q with %d", tt.query, tt.better, b, tt.worse, w)
+		}
+	}
+}
+
+// labels returns the labels of the items the picker shows, in order
+func labels(p *picker) []string {
+	var got []string
+	for _, item := range p.visible {
+		got = append(got, item.label)
+	}
+	return got
+}
+
+func TestPicker(t *testing.T) {
+	m, _ := newTestModel(t, nil)
+	for _, id := range []string{"pod-a", "db-main"} {
+		if err := m.tmux.AttachResourceTerminal(id); err != nil {
+			t.Fatalf("AttachResourceTerminal %s: %v", id, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := m.tmux.AttachAIChat(); err != nil {
+			t.Fatalf("AttachAIChat: %v", err)
+		}
+	}
+	if err := m.tmux.RenameAIChat("ai-2", "db migration"); err != nil {
+		t.Fatalf("RenameAIChat: %v", err)
+	}
+
+	press(m, "A")
+	if m.picker == nil {
+		t.Fatalf("A did not open the picker")
+	}
+
+	steps := []struct {
+		keys []string
+		want []string
+	}{
+		// AI chats in tab order, then resources by ID
+		{nil, []string{"ai-1", "db migration (ai-2)", "Database (db-main)", "pod-a"}},
+		{[]string{"ctrl+a"}, []string{"ai-1", "db migration (ai-2)"}},
+		{[]string{"ctrl+r"}, []string{"Database (db-main)", "pod-a"}},
+		{[]string{"ctrl+t"}, []string{"ai-1", "db migration (ai-2)", "Database (db-main)", "pod-a"}},
+		// Ranked by score whatever the kind
+		{[]string{"d", "b"}, []string{"db migration (ai-2)", "Database (db-main)"}},
+		{[]string{"backspace", "backspace", "m", "a", "i", "n"}, []string{"Database (db-main)", "db migration (ai-2)"}},
+		{[]string{"ctrl+a"}, []string{"db migration (ai-2)"}},
+	}
+	for _, step := range steps {
+		press(m, step.keys...)
+		if got := labels(m.picker); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("after %q: picker shows %q, want %q", step.keys, got, step.want)
+		}
+	}
+
+	// ENTER switches to the highlighted item
+	press(m, "enter")
+	if m.picker != nil {
+		t.Errorf("the picker is still open after ENTER")
+	}
+	if got := m.tmux.GetActiveAIChat(); got != "ai-2" {
+		t.Errorf("active AI chat = %q, want ai-2", got)
+	}
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xunzhou/muxctl/pkg/config"
+	"github.com/xunzhou/muxctl/pkg/provider"
+)
+
+// providerTimeout bounds a single discovery run so a hung kubectl or docker
+// cannot stall a provider forever
+const providerTimeout = 20 * time.Second
+
+// providerState tracks one resource provider and what it last discovered
+type providerState struct {
+	provider  provider.ResourceProvider
+	interval  time.Duration
+	resources []config.Resource
+}
+
+// providerMsg reports the result of polling a provider
+type providerMsg struct {
+	index     int
+	resources []config.Resource
+	err       error
+}
+
+// providerTickMsg triggers the next poll of a provider
+type providerTickMsg int
+
+// pollProvider discovers resources from provider i in the background
+func (m *Model) pollProvider(i int) tea.Cmd {
+	p := m.providers[i].provider
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+		defer cancel()
+
+		resources, err := p.Resources(ctx)
+		return providerMsg{index: i, resources: resources, err: err}
+	}
+}
+
+// handleProviderMsg stores discovered resources and schedules the next poll
+// On error the previously discovered resources are kept
+func (m *Model) handleProviderMsg(msg providerMsg) tea.Cmd {
+	state := &m.providers[msg.index]
+	if msg.err != nil {
+		m.message = fmt.Sprintf("%s: %v", state.provider.Name(), msg.err)
+	} else {
+		state.resources = msg.resources
+		m.rebuildResources()
+	}
+
+	return tea.Tick(state.interval, func(time.Time) tea.Msg {
+		return providerTickMsg(msg.index)
+	})
+}
+
+// rebuildResources merges static and discovered resources, registers how to
+// start each one, and keeps the selection on the same resource where possible
+func (m *Model) rebuildResources() {
+	selectedID := ""
+	selectedAIRow := -1
+	if m.selectedIdx < len(m.resources) {
+		selectedID = m.resources[m.selectedIdx].ID
+	} else if _, ok := m.selectedAIChat(); ok {
+		selectedAIRow = m.selectedIdx - len(m.resources)
+	}
+
+	seen := make(map[string]bool)
+	resources := make([]config.Resource, 0, len(m.static))
+	add := func(res config.Resource) {
+		// Static resources win over discovered ones with the same ID
+		if seen[res.ID] {
+			return
+		}
+		seen[res.ID] = true
+		resources = append(resources, res)
+		m.tmux.SetResourceSpec(res.ID, resourceSpec(res))
+	}
+	for _, res := range m.static {
+		add(res)
+	}
+	for _, state := range m.providers {
+		for _, res := range state.resources {
+			add(res)
+		}
+	}
+	m.resources = resources
+
+	// Keep an AI chat selected even though the resources above it changed
+	if selectedAIRow >= 0 {
+		m.selectedIdx = len(m.resources) + selectedAIRow
+		return
+	}
+
+	m.selectedIdx = 0
+	for i, res := range m.resources {
+		if res.ID == selectedID {
+			m.selectedIdx = i
+			break
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xunzhou/muxctl/pkg/config"
+	"github.com/xunzhou/muxctl/pkg/tmux"
+)
+
+func TestRebuildResourcesKeepsSelection(t *testing.T) {
+	cfg := config.Default()
+	cfg.Resources = nil
+	m, _ := newTestModel(t, cfg)
+	m.providers = []providerState{{interval: time.Minute}}
+	poll := func(ids ...string) {
+		var resources []config.Resource
+		for _, id := range ids {
+			resources = append(resources, config.Resource{ID: id})
+		}
+		m.handleProviderMsg(providerMsg{index: 0, resources: resources})
+	}
+
+	// Resources appearing under an empty list leave the cursor on the first
+	poll("pod-a", "pod-b")
+	if m.selectedIdx != 0 {
+		t.Errorf("selectedIdx = %d after the first resources appeared, want 0", m.selectedIdx)
+	}
+	poll()
+
+	for i := 0; i < 2; i++ {
+		if _, err := m.tmux.StartAIChat(tmux.AIChatOptions{}); err != nil {
+			t.Fatalf("StartAIChat: %v", err)
+		}
+	}
+	m.selectedIdx = 1
+
+	for _, step := range []struct {
+		resources []string
+		want      int
+	}{
+		{nil, 1},
+		{[]string{"pod-a", "pod-b"}, 3},
+		{[]string{"pod-b"}, 2},
+		{nil, 1},
+	} {
+		poll(step.resources...)
+		chat, ok := m.selectedAIChat()
+		if m.selectedIdx != step.want || !ok || chat.ID != "ai-2" {
+			t.Errorf("with resources %q selectedIdx = %d (%s), want %d (ai-2)", step.resources, m.selectedIdx, chat.ID, step.want)
+		}
+	}
+
+	// A selected resource is followed to its new row
+	poll("pod-a", "pod-b")
+	m.selectedIdx = 1
+	poll("pod-0", "pod-a", "pod-b")
+	if m.selectedIdx != 2 {
+		t.Errorf("selectedIdx = %d after a resource was added above pod-b, want 2", m.selectedIdx)
+	}
+}
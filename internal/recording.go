@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/xunzhou/muxctl/pkg/record"
+)
+
+// toggleRecording starts or stops recording a resource's pane into the
+// recording directory
+func (m *Model) toggleRecording(resourceID string) {
+	if m.tmux.GetRecording(resourceID) != "" {
+		path, err := m.tmux.StopRecording(resourceID)
+		if err != nil {
+			m.message = fmt.Sprintf("Error: %v", err)
+			return
+		}
+		m.message = fmt.Sprintf("Saved recording: %s", path)
+		return
+	}
+
+	if _, open := m.tmux.GetResourcePanes()[resourceID]; !open {
+		m.message = fmt.Sprintf("Open %s before recording it", resourceID)
+		return
+	}
+	path, err := record.Path(m.recordingDir, resourceID, time.Now())
+	if err == nil {
+		err = m.tmux.StartRecording(resourceID, path)
+	}
+	if err != nil {
+		m.message = fmt.Sprintf("Error: %v", err)
+		return
+	}
+	m.message = fmt.Sprintf("Recording %s to %s", resourceID, path)
+}
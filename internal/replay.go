@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xunzhou/muxctl/pkg/record"
+)
+
+// maxReplayIdle caps the pause between two events so long idle stretches in
+// a recording do not stall playback
+const maxReplayIdle = 2 * time.Second
+
+// Replay plays a recording back by writing its output to the terminal with
+// the recorded timing
+// It is meant to run with tea.WithoutRenderer: the recorded escape sequences
+// drive the terminal directly and View is unused
+type Replay struct {
+	rec    *record.Recording
+	out    io.Writer
+	next   int     // Index of the next event to write
+	speed  float64 // Playback speed multiplier
+	paused bool
+	gen    int // Bumped to invalidate pending ticks on pause or speed change
+}
+
+// replayTickMsg asks the replay to write the next event
+type replayTickMsg struct {
+	gen int
+}
+
+// NewReplay creates a replay of rec that writes to out at the given speed
+func NewReplay(rec *record.Recording, out io.Writer, speed float64) *Replay {
+	if speed <= 0 {
+		speed = 1
+	}
+	return &Replay{rec: rec, out: out, speed: speed}
+}
+
+func (r *Replay) Init() tea.Cmd {
+	// Start from a clear screen like the pane did
+	fmt.Fprint(r.out, "\x1b[2J\x1b[H")
+	return r.schedule()
+}
+
+// schedule waits for the next event's time relative to the previous one
+func (r *Replay) schedule() tea.Cmd {
+	if r.paused || r.next >= len(r.rec.Events) {
+		return nil
+	}
+	var delay time.Duration
+	if r.next > 0 {
+		gap := r.rec.Events[r.next].Time - r.rec.Events[r.next-1].Time
+		delay = time.Duration(gap / r.speed * float64(time.Second))
+	}
+	if delay > maxReplayIdle {
+		delay = maxReplayIdle
+	}
+	gen := r.gen
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return replayTickMsg{gen: gen}
+	})
+}
+
+func (r *Replay) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case replayTickMsg:
+		if msg.gen != r.gen || r.next >= len(r.rec.Events) {
+			return r, nil
+		}
+		io.WriteString(r.out, r.rec.Events[r.next].Data)
+		r.next++
+		if r.next == len(r.rec.Events) {
+			fmt.Fprint(r.out, "\r\n\x1b[0m[end of recording - q to quit]")
+		}
+		return r, r.schedule()
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c", "esc":
+			return r, tea.Quit
+		case " ":
+			// Pause or resume
+			r.paused = !r.paused
+			r.gen++
+			return r, r.schedule()
+		case "+", "=":
+			r.speed *= 2
+			r.gen++
+			return r, r.schedule()
+		case "-":
+			r.speed /= 2
+			r.gen++
+			return r, r.schedule()
+		}
+	}
+	return r, nil
+}
+
+func (r *Replay) View() string {
+	return ""
+}
@@ -2,7 +2,9 @@ package ai
 
 import (
 	"fmt"
-	"path/filepath"
+	"strings"
+
+	"github.com/xunzhou/muxctl/pkg/rundir"
 )
 
 // ConversationAction represents an action to perform on a conversation
@@ -15,6 +17,10 @@ const (
 	ConvActionResize ConversationAction = "resize"
 )
 
+// SocketOption is the tmux session option holding the conversation socket's
+// path, e.g. for `nc -U "$(tmux show-options -v @muxctl-ai-socket)"`
+const SocketOption = "@muxctl-ai-socket"
+
 // ConversationRequestContext provides context for a conversation
 type ConversationRequestContext struct {
 	AlertFingerprint string `json:"alert_fingerprint"`
@@ -74,7 +80,26 @@ func (r *ConversationRequest) Validate() error {
 	return nil
 }
 
-// SocketPath returns the Unix socket path for the given session
-func SocketPath(sessionName string) string {
-	return filepath.Join("/tmp", fmt.Sprintf("muxctl-%s-ai.sock", sessionName))
+// SocketPath returns the Unix socket path for a session on the tmux server
+// listening on serverSocket
+func SocketPath(serverSocket, sessionName string) (string, error) {
+	return rundir.Path(serverSocket, sessionName, "ai.sock")
+}
+
+// Prompt renders the context as the opening message of a conversation
+func (c ConversationRequestContext) Prompt() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Investigating alert %s", c.AlertFingerprint))
+	if c.Cluster != "" {
+		b.WriteString(fmt.Sprintf(" in cluster %s", c.Cluster))
+	}
+	if c.Namespace != "" {
+		b.WriteString(fmt.Sprintf(", namespace %s", c.Namespace))
+	}
+	b.WriteString(".")
+	if c.InitialSummary != "" {
+		b.WriteString("\n\n")
+		b.WriteString(c.InitialSummary)
+	}
+	return b.String()
 }
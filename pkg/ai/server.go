@@ -0,0 +1,39 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/xunzhou/muxctl/pkg/socket"
+)
+
+// ConversationHandler carries out validated conversation requests
+type ConversationHandler interface {
+	HandleConversation(req *ConversationRequest) *ConversationResponse
+}
+
+// Listen serves conversation requests on a Unix socket at path
+// Each line is a ConversationRequest and is answered by exactly one
+// ConversationResponse line
+func Listen(path string, handler ConversationHandler) (*socket.Server, error) {
+	return socket.Listen(path, func(line []byte) interface{} {
+		return handle(handler, line)
+	})
+}
+
+// handle decodes and validates one request line before passing it on
+func handle(handler ConversationHandler, line []byte) *ConversationResponse {
+	var req ConversationRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &ConversationResponse{Error: fmt.Sprintf("invalid request: %v", err)}
+	}
+	if err := req.Validate(); err != nil {
+		return &ConversationResponse{ConversationID: req.ConversationID, Error: err.Error()}
+	}
+
+	resp := handler.HandleConversation(&req)
+	if resp == nil {
+		return &ConversationResponse{ConversationID: req.ConversationID, Error: "no response"}
+	}
+	return resp
+}
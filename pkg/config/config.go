@@ -0,0 +1,373 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Resource describes a terminal resource listed in the TUI
+type Resource struct {
+	ID      string            `yaml:"id"`
+	Name    string            `yaml:"name,omitempty"`    // Display name (defaults to ID)
+	Group   string            `yaml:"group,omitempty"`   // Heading the resource is listed under
+	Dir     string            `yaml:"dir,omitempty"`     // Working directory
+	Env     map[string]string `yaml:"env,omitempty"`     // Extra environment variables
+	Command string            `yaml:"command,omitempty"` // Startup command (defaults to the user's shell)
+	Prompt  string            `yaml:"prompt,omitempty"`  // PS1 prompt (defaults to "[id] $ ")
+}
+
+// DisplayName returns the name shown in the TUI
+func (r Resource) DisplayName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.ID
+}
+
+// Provider types
+const (
+	ProviderKubernetes = "kubernetes"
+	ProviderDocker     = "docker"
+	ProviderSSH        = "ssh"
+)
+
+// Provider configures a source of dynamically discovered resources
+type Provider struct {
+	Type          string        `yaml:"type"`                     // kubernetes, docker or ssh
+	Interval      time.Duration `yaml:"interval,omitempty"`       // How often to re-discover (default 30s)
+	Context       string        `yaml:"context,omitempty"`        // kubernetes: kubeconfig context
+	Namespace     string        `yaml:"namespace,omitempty"`      // kubernetes: namespace (default: current)
+	AllNamespaces bool          `yaml:"all_namespaces,omitempty"` // kubernetes: list pods in every namespace
+	Shell         string        `yaml:"shell,omitempty"`          // kubernetes/docker: shell to exec (default sh)
+	Path          string        `yaml:"path,omitempty"`           // ssh: config file (default ~/.ssh/config)
+}
+
+// AIBackend configures a CLI that AI chats run
+type AIBackend struct {
+	Name        string            `yaml:"name"`
+	Command     []string          `yaml:"command"`                // Program and arguments
+	Env         map[string]string `yaml:"env,omitempty"`          // Extra environment variables
+	Prefix      string            `yaml:"prefix,omitempty"`       // Status bar tab prefix (defaults to name)
+	SessionArgs []string          `yaml:"session_args,omitempty"` // Arguments giving a new chat its session ID; "{session}" is replaced
+	ResumeArgs  []string          `yaml:"resume_args,omitempty"`  // Arguments resuming a session; "{session}" is replaced
+	PromptArgs  []string          `yaml:"prompt_args,omitempty"`  // Arguments passing a prompt; "{prompt}" is replaced (default: the prompt alone, last)
+}
+
+// DefaultAIBackend is used when the config file defines no AI backends
+func DefaultAIBackend() AIBackend {
+	return AIBackend{
+		Name:        "claude",
+		Command:     []string{"claude"},
+		Prefix:      "ai",
+		SessionArgs: []string{"--session-id", "{session}"},
+		ResumeArgs:  []string{"--resume", "{session}"},
+	}
+}
+
+// Theme presets
+const (
+	ThemeDark  = "dark"
+	ThemeLight = "light"
+)
+
+// Theme styles the status bar and pane borders
+// Styles use tmux syntax, e.g. "bg=colour39,fg=black", and override the preset
+type Theme struct {
+	Preset            string `yaml:"preset,omitempty"`              // dark or light (default dark)
+	StatusStyle       string `yaml:"status_style,omitempty"`        // Status bar
+	BorderStyle       string `yaml:"border_style,omitempty"`        // Inactive pane borders
+	ActiveBorderStyle string `yaml:"active_border_style,omitempty"` // Active pane border
+	ActiveTab         string `yaml:"active_tab,omitempty"`          // Tab shown in the bottom pane
+	InactiveTab       string `yaml:"inactive_tab,omitempty"`        // Other tabs of the same kind
+	DimTab            string `yaml:"dim_tab,omitempty"`             // Tabs of the kind not shown
+	RecordingTab      string `yaml:"recording_tab,omitempty"`       // Marker on tabs of recorded resources
+}
+
+// Layout orientations
+const (
+	OrientationVertical   = "vertical"   // TUI above the terminal
+	OrientationHorizontal = "horizontal" // TUI left of the terminal
+)
+
+// Layout configures how the tmux window is split between the TUI and the
+// terminal
+type Layout struct {
+	Orientation    string `yaml:"orientation,omitempty"`     // vertical or horizontal (default vertical)
+	TUISize        string `yaml:"tui_size,omitempty"`        // Rows (columns when horizontal) or a percentage, e.g. "30%" (default 50%)
+	PreserveResize bool   `yaml:"preserve_resize,omitempty"` // Keep manual resizes when switching panes
+}
+
+// Recording configures where resource pane recordings are written
+type Recording struct {
+	Dir string `yaml:"dir,omitempty"` // Log directory (default $XDG_STATE_HOME/muxctl/recordings)
+}
+
+// Context configures what the send-context action pastes into an AI chat
+type Context struct {
+	Lines    int    `yaml:"lines,omitempty"`    // Lines captured when nothing is selected (default 50)
+	Template string `yaml:"template,omitempty"` // Go text/template for the prompt (default DefaultContextTemplate)
+}
+
+// DefaultContextTemplate wraps captured terminal output in a prompt
+// Fields: .ID, .Name, .Group, .Command, .Lines, .Selection and .Content
+const DefaultContextTemplate = `Terminal output from {{.Name}}{{if ne .Name .ID}} ({{.ID}}){{end}}{{with .Group}} in {{.}}{{end}}{{with .Command}}, running ` + "`{{.}}`" + `{{end}}:
+
+` + "```" + `
+{{.Content}}
+` + "```" + `
+`
+
+// RedactionRule is a user-defined secret pattern
+type RedactionRule struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"` // Go regexp; a group named "secret" limits what is replaced
+}
+
+// Redaction configures how secrets are removed from captured pane content
+type Redaction struct {
+	Disable []string        `yaml:"disable,omitempty"` // Builtin detectors to turn off
+	Rules   []RedactionRule `yaml:"rules,omitempty"`   // Extra detectors, applied after the builtin ones
+}
+
+// Exit modes
+const (
+	ExitTeardown    = "teardown"     // Close muxctl's panes and windows, keep the session
+	ExitDetach      = "detach"       // Leave resource and AI chat panes running for the next muxctl
+	ExitKillSession = "kill-session" // Kill the whole tmux session
+)
+
+// CheckExitMode reports whether mode is a known exit mode
+func CheckExitMode(mode string) error {
+	switch mode {
+	case ExitTeardown, ExitDetach, ExitKillSession:
+		return nil
+	}
+	return fmt.Errorf("unknown exit mode %q (want %s, %s or %s)", mode, ExitTeardown, ExitDetach, ExitKillSession)
+}
+
+// Config is the muxctl configuration file
+type Config struct {
+	Resources  []Resource  `yaml:"resources"`
+	Providers  []Provider  `yaml:"providers"`
+	AIBackends []AIBackend `yaml:"ai_backends"` // The first is selected on startup
+	Theme      Theme       `yaml:"theme"`
+	ExitMode   string      `yaml:"exit_mode"` // teardown, detach or kill-session (default teardown)
+	Layout     Layout      `yaml:"layout"`
+	Recording  Recording   `yaml:"recording"`
+	Context    Context     `yaml:"context"`
+	Redaction  Redaction   `yaml:"redaction"`
+}
+
+// DefaultPath returns $XDG_CONFIG_HOME/muxctl/config.yaml, falling back to
+// ~/.config/muxctl/config.yaml
+func DefaultPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "muxctl", "config.yaml")
+}
+
+// Default returns the configuration used when no config file exists
+func Default() *Config {
+	return &Config{
+		Resources: []Resource{
+			{ID: "pod-a"},
+			{ID: "pod-b"},
+			{ID: "pod-c"},
+			{ID: "service-x"},
+			{ID: "service-y"},
+		},
+		AIBackends: []AIBackend{DefaultAIBackend()},
+		Theme:      Theme{Preset: ThemeDark},
+		ExitMode:   ExitTeardown,
+		Layout:     Layout{Orientation: OrientationVertical},
+		Recording:  Recording{Dir: defaultRecordingDir()},
+		Context:    Context{Lines: 50, Template: DefaultContextTemplate},
+	}
+}
+
+// defaultRecordingDir returns $XDG_STATE_HOME/muxctl/recordings, falling
+// back to ~/.local/state/muxctl/recordings
+func defaultRecordingDir() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "muxctl", "recordings")
+}
+
+// Load reads the config file at path
+// An empty path means DefaultPath; a missing file at the default path yields
+// Default(), while a missing file at an explicit path is an error
+func Load(path string) (*Config, error) {
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return Default(), nil
+		}
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// validate checks the config, fills in defaults and expands ~ in paths
+func (c *Config) validate() error {
+	seen := make(map[string]bool)
+	for i := range c.Resources {
+		res := &c.Resources[i]
+		if res.ID == "" {
+			return fmt.Errorf("resource %d has no id", i+1)
+		}
+		if seen[res.ID] {
+			return fmt.Errorf("duplicate resource id %q", res.ID)
+		}
+		seen[res.ID] = true
+		res.Dir = expandHome(res.Dir)
+	}
+
+	for i := range c.Providers {
+		p := &c.Providers[i]
+		switch p.Type {
+		case ProviderKubernetes, ProviderDocker, ProviderSSH:
+		default:
+			return fmt.Errorf("provider %d: unknown type %q", i+1, p.Type)
+		}
+		if p.Interval == 0 {
+			p.Interval = 30 * time.Second
+		}
+		p.Path = expandHome(p.Path)
+	}
+
+	if len(c.AIBackends) == 0 {
+		c.AIBackends = []AIBackend{DefaultAIBackend()}
+	}
+	backends := make(map[string]bool)
+	for i := range c.AIBackends {
+		b := &c.AIBackends[i]
+		if b.Name == "" {
+			return fmt.Errorf("AI backend %d has no name", i+1)
+		}
+		if backends[b.Name] {
+			return fmt.Errorf("duplicate AI backend %q", b.Name)
+		}
+		backends[b.Name] = true
+		if len(b.Command) == 0 {
+			return fmt.Errorf("AI backend %q has no command", b.Name)
+		}
+		if b.Prefix == "" {
+			b.Prefix = b.Name
+		}
+	}
+
+	switch c.Theme.Preset {
+	case "":
+		c.Theme.Preset = ThemeDark
+	case ThemeDark, ThemeLight:
+	default:
+		return fmt.Errorf("unknown theme preset %q", c.Theme.Preset)
+	}
+
+	if c.ExitMode == "" {
+		c.ExitMode = ExitTeardown
+	}
+	if err := CheckExitMode(c.ExitMode); err != nil {
+		return err
+	}
+
+	switch c.Layout.Orientation {
+	case "":
+		c.Layout.Orientation = OrientationVertical
+	case OrientationVertical, OrientationHorizontal:
+	default:
+		return fmt.Errorf("unknown layout orientation %q", c.Layout.Orientation)
+	}
+	if c.Layout.TUISize != "" && !validSize(c.Layout.TUISize) {
+		return fmt.Errorf("invalid layout tui_size %q (want rows or a percentage such as 30%%)", c.Layout.TUISize)
+	}
+
+	if c.Recording.Dir == "" {
+		c.Recording.Dir = defaultRecordingDir()
+	}
+	c.Recording.Dir = expandHome(c.Recording.Dir)
+
+	if c.Context.Lines == 0 {
+		c.Context.Lines = 50
+	}
+	if c.Context.Lines < 0 {
+		return fmt.Errorf("invalid context lines %d", c.Context.Lines)
+	}
+	if c.Context.Template == "" {
+		c.Context.Template = DefaultContextTemplate
+	}
+	if _, err := template.New("context").Parse(c.Context.Template); err != nil {
+		return fmt.Errorf("invalid context template: %w", err)
+	}
+
+	for i, rule := range c.Redaction.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("redaction rule %d has no name", i+1)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("redaction rule %q: %w", rule.Name, err)
+		}
+		if re.MatchString("") {
+			return fmt.Errorf("redaction rule %q: pattern matches empty text", rule.Name)
+		}
+	}
+	return nil
+}
+
+// validSize reports whether size is a positive number of rows or columns, or
+// a percentage between 1% and 99%
+func validSize(size string) bool {
+	n, err := strconv.Atoi(strings.TrimSuffix(size, "%"))
+	if err != nil || n < 1 {
+		return false
+	}
+	return !strings.HasSuffix(size, "%") || n < 100
+}
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
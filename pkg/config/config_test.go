@@ -0,0 +1,147 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// load writes yaml to a config file and loads it
+func load(t *testing.T, yaml string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load without a config file: %v", err)
+	}
+	if len(cfg.Resources) != len(Default().Resources) {
+		t.Errorf("resources = %v, want the defaults", cfg.Resources)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("Load accepted a missing explicit config file")
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_STATE_HOME", "")
+
+	cfg, err := load(t, `
+resources:
+  - id: pod-a
+    dir: ~/src
+providers:
+  - type: ssh
+    path: ~/.ssh/work
+ai_backends:
+  - name: gemini
+    command: [gemini]
+`)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"resource dir", cfg.Resources[0].Dir, filepath.Join(home, "src")},
+		{"provider path", cfg.Providers[0].Path, filepath.Join(home, ".ssh", "work")},
+		{"provider interval", cfg.Providers[0].Interval, 30 * time.Second},
+		{"backend prefix", cfg.AIBackends[0].Prefix, "gemini"},
+		{"theme", cfg.Theme.Preset, ThemeDark},
+		{"exit mode", cfg.ExitMode, ExitTeardown},
+		{"orientation", cfg.Layout.Orientation, OrientationVertical},
+		{"recording dir", cfg.Recording.Dir, filepath.Join(home, ".local", "state", "muxctl", "recordings")},
+		{"context lines", cfg.Context.Lines, 50},
+		{"context template", cfg.Context.Template, DefaultContextTemplate},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	// Without backends the default one is used
+	cfg, err = load(t, "resources: []\n")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.AIBackends) != 1 || cfg.AIBackends[0].Name != DefaultAIBackend().Name {
+		t.Errorf("AI backends = %v, want the default", cfg.AIBackends)
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string // Part of the error
+	}{
+		{"resource without id", "resources: [{name: a}]", "has no id"},
+		{"duplicate resource", "resources: [{id: a}, {id: a}]", "duplicate resource"},
+		{"unknown provider", "providers: [{type: nomad}]", "unknown type"},
+		{"backend without command", "ai_backends: [{name: x}]", "has no command"},
+		{"duplicate backend", "ai_backends: [{name: x, command: [x]}, {name: x, command: [x]}]", "duplicate AI backend"},
+		{"unknown theme", "theme: {preset: solarized}", "unknown theme"},
+		{"unknown exit mode", "exit_mode: suspend", "unknown exit mode"},
+		{"unknown orientation", "layout: {orientation: diagonal}", "unknown layout orientation"},
+		{"zero size", `layout: {tui_size: "0"}`, "invalid layout tui_size"},
+		{"full percentage", `layout: {tui_size: "100%"}`, "invalid layout tui_size"},
+		{"size with unit", `layout: {tui_size: "30px"}`, "invalid layout tui_size"},
+		{"negative context lines", "context: {lines: -1}", "invalid context lines"},
+		{"bad template", `context: {template: "{{.Content"}`, "invalid context template"},
+		{"unnamed redaction rule", `redaction: {rules: [{pattern: "x"}]}`, "has no name"},
+		{"bad redaction pattern", `redaction: {rules: [{name: x, pattern: "("}]}`, `redaction rule "x"`},
+		{"empty-matching redaction pattern", `redaction: {rules: [{name: x, pattern: "x*"}]}`, "matches empty text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, tt.yaml)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidSize(t *testing.T) {
+	for size, want := range map[string]bool{
+		"1": true, "40": true, "1%": true, "30%": true, "99%": true,
+		"0": false, "-5": false, "0%": false, "100%": false, "%": false, "": false, "30 %": false,
+	} {
+		if got := validSize(size); got != want {
+			t.Errorf("validSize(%q) = %v, want %v", size, got, want)
+		}
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	for path, want := range map[string]string{
+		"~":        home,
+		"~/logs":   filepath.Join(home, "logs"),
+		"~user/x":  "~user/x",
+		"/tmp/~/x": "/tmp/~/x",
+		"":         "",
+	} {
+		if got := expandHome(path); got != want {
+			t.Errorf("expandHome(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
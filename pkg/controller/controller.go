@@ -1,6 +1,10 @@
 package controller
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/xunzhou/muxctl/pkg/tmux"
 )
 
@@ -44,44 +48,71 @@ type Controller interface {
 
 	// FocusPane focuses a pane by role
 	FocusPane(role Role) error
+
+	// PaneForRole returns the ID of the pane that currently fills a role
+	PaneForRole(role Role) (string, error)
 }
 
+// Layout types
+const (
+	LayoutTwoPane   = "two-pane"   // TUI above the manager's swappable terminal
+	LayoutThreePane = "three-pane" // TUI left, terminal center, AI right
+)
+
 // Layout represents a tmux layout configuration
 type Layout struct {
-	Type string // "two-pane", "three-pane", etc.
+	Type string // LayoutTwoPane or LayoutThreePane
 }
 
 // DefaultLayout returns the default 2-pane layout
 func DefaultLayout() Layout {
 	return Layout{
-		Type: "two-pane",
+		Type: LayoutTwoPane,
+	}
+}
+
+// ThreePaneLayout returns the TUI | terminal | AI layout
+// Every pane starts with the user's shell; start the AI CLI in the right one
+// with RunInWindow(string(RoleRight), ...)
+func ThreePaneLayout() Layout {
+	return Layout{
+		Type: LayoutThreePane,
 	}
 }
 
 // controller implements the Controller interface
 type controller struct {
 	manager *tmux.Manager
+	opts    []tmux.Option   // Options for the manager, e.g. the server socket
+	runner  tmux.Runner     // Runs commands before the manager exists
+	layout  Layout          // Layout built by Init
+	roles   map[Role]string // role -> pane ID filling it
 }
 
 // New creates a new Controller
-func New() Controller {
+// Options such as tmux.WithSocketName select a dedicated tmux server
+func New(opts ...tmux.Option) Controller {
 	// Try to create a manager if we're already in a tmux session
-	mgr, _ := tmux.NewManager()
+	mgr, _ := tmux.NewManager(opts...)
 	return &controller{
 		manager: mgr,
+		opts:    opts,
+		runner:  tmux.NewRunner(opts...),
+		roles:   make(map[Role]string),
 	}
 }
 
 // Available checks if tmux is available
 func (c *controller) Available() bool {
-	// Check if tmux is available by trying to run a simple command
-	_, err := tmuxCmd("display-message", "-p", "#{version}")
+	// Check if tmux is available by asking for its version, which works
+	// before a dedicated server has been started
+	_, err := c.tmuxCmd("-V")
 	return err == nil
 }
 
 // SessionExists checks if a session exists
 func (c *controller) SessionExists(name string) bool {
-	_, err := tmuxCmd("has-session", "-t", name)
+	_, err := c.tmuxCmd("has-session", "-t", name)
 	return err == nil
 }
 
@@ -92,29 +123,121 @@ func (c *controller) EnsureSession(name string) error {
 	}
 
 	// Create new session detached
-	_, err := tmuxCmd("new-session", "-d", "-s", name)
+	_, err := c.tmuxCmd("new-session", "-d", "-s", name)
 	return err
 }
 
 // Init initializes a session with the given layout
 func (c *controller) Init(sessionName string, layout Layout) error {
 	// If we don't have a manager yet, create one
+	// Outside the session, e.g. on a dedicated server, the manager targets it
 	if c.manager == nil {
-		mgr, err := tmux.NewManager()
+		mgr, err := tmux.NewManager(append(c.opts, tmux.WithTarget(sessionName))...)
 		if err != nil {
 			return err
 		}
 		c.manager = mgr
 	}
 
-	// Setup the layout
-	if err := c.manager.Setup(); err != nil {
-		return err
+	c.layout = layout
+	c.roles = make(map[Role]string)
+
+	switch layout.Type {
+	case LayoutTwoPane, "":
+		// The manager's layout; the center role follows its bottom pane
+		if err := c.manager.Setup(); err != nil {
+			return err
+		}
+		c.roles[RoleLeft] = c.manager.GetTUIPane()
+	case LayoutThreePane:
+		return c.initThreePane()
+	default:
+		return fmt.Errorf("unknown layout %q", layout.Type)
+	}
+
+	return nil
+}
+
+// initThreePane splits the TUI pane into TUI, terminal and AI columns of
+// 25%, 50% and 25%
+// Columns left by an earlier Init are adopted instead of split again
+func (c *controller) initThreePane() error {
+	left := c.manager.GetTUIPane()
+	output, err := c.tmuxCmd("list-panes", "-t", left, "-F", "#{pane_left} #{pane_id}")
+	if err != nil {
+		return fmt.Errorf("list panes: %w", err)
+	}
+	lines := strings.Split(output, "\n")
+	switch len(lines) {
+	case 1:
+		// Only the TUI: split it into columns side by side
+	case 3:
+		return c.adoptThreePane(lines)
+	default:
+		return fmt.Errorf("unexpected pane count: %d (expected 1 or 3)", len(lines))
+	}
+
+	center, err := c.tmuxCmd("split-window", "-h", "-p", "75", "-t", left, "-P", "-F", "#{pane_id}")
+	if err != nil {
+		return fmt.Errorf("create center pane: %w", err)
+	}
+	right, err := c.tmuxCmd("split-window", "-h", "-p", "33", "-t", center, "-P", "-F", "#{pane_id}")
+	if err != nil {
+		return fmt.Errorf("create right pane: %w", err)
+	}
+
+	c.roles[RoleLeft] = left
+	c.roles[RoleCenter] = center
+	c.roles[RoleRight] = right
+	c.tmuxCmd("select-pane", "-t", left)
+	return nil
+}
+
+// adoptThreePane gives the roles to the columns of an existing three-pane
+// layout, given as "pane_left pane_id" lines
+func (c *controller) adoptThreePane(lines []string) error {
+	type column struct {
+		left int
+		pane string
+	}
+	columns := make([]column, 0, len(lines))
+	for _, line := range lines {
+		var col column
+		if _, err := fmt.Sscan(line, &col.left, &col.pane); err != nil {
+			return fmt.Errorf("parse pane %q: %w", line, err)
+		}
+		columns = append(columns, col)
 	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].left < columns[j].left })
 
+	c.roles[RoleLeft] = columns[0].pane
+	c.roles[RoleCenter] = columns[1].pane
+	c.roles[RoleRight] = columns[2].pane
 	return nil
 }
 
+// PaneForRole returns the ID of the pane that currently fills a role
+func (c *controller) PaneForRole(role Role) (string, error) {
+	if c.manager != nil && role == RoleCenter && c.layout.Type != LayoutThreePane {
+		if pane := c.manager.GetBottomPane(); pane != "" {
+			return pane, nil
+		}
+	}
+	if pane, ok := c.roles[role]; ok {
+		return pane, nil
+	}
+	return "", fmt.Errorf("no pane has role %q", role)
+}
+
+// resolve turns a role name into the pane filling it; other targets are
+// returned unchanged
+func (c *controller) resolve(target string) string {
+	if pane, err := c.PaneForRole(Role(target)); err == nil {
+		return pane
+	}
+	return target
+}
+
 // GetManager returns the underlying tmux.Manager
 func (c *controller) GetManager() *tmux.Manager {
 	return c.manager
@@ -123,7 +246,7 @@ func (c *controller) GetManager() *tmux.Manager {
 // CreateWindow creates a new tmux window and returns its ID
 func (c *controller) CreateWindow(name string) (string, error) {
 	// Create window detached and get its ID
-	windowID, err := tmuxCmd("new-window", "-d", "-n", name, "-P", "-F", "#{window_id}")
+	windowID, err := c.tmuxCmd("new-window", "-d", "-n", name, "-P", "-F", "#{window_id}")
 	if err != nil {
 		return "", err
 	}
@@ -131,35 +254,85 @@ func (c *controller) CreateWindow(name string) (string, error) {
 }
 
 // RunInWindow runs a command in a window
+// window may be a role; opts["cwd"] sets the working directory and every
+// other key is set as an environment variable
 func (c *controller) RunInWindow(window string, cmd []string, opts map[string]string) error {
 	// Kill existing panes and respawn with command
-	args := []string{"respawn-pane", "-t", window, "-k"}
+	args := []string{"respawn-pane", "-t", c.resolve(window), "-k"}
+	keys := make([]string, 0, len(opts))
+	for key := range opts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "cwd" {
+			args = append(args, "-c", opts[key])
+		} else {
+			args = append(args, "-e", key+"="+opts[key])
+		}
+	}
 	args = append(args, cmd...)
 
-	_, err := tmuxCmd(args...)
+	_, err := c.tmuxCmd(args...)
 	return err
 }
 
 // SwapPanesByTarget swaps two panes by their targets
+// Either target may be a role; roles keep their position in the layout, so
+// afterwards they are filled by the pane swapped in
 func (c *controller) SwapPanesByTarget(src, dst string) error {
-	_, err := tmuxCmd("swap-pane", "-s", src, "-t", dst)
-	return err
+	srcPane, err := c.tmuxCmd("display-message", "-p", "-t", c.resolve(src), "#{pane_id}")
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", src, err)
+	}
+	dstPane, err := c.tmuxCmd("display-message", "-p", "-t", c.resolve(dst), "#{pane_id}")
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", dst, err)
+	}
+
+	// In the two-pane layout the manager owns the bottom pane, so swaps into
+	// it go through the manager to keep its bookkeeping right
+	if c.manager != nil && c.layout.Type != LayoutThreePane {
+		switch bottom := c.manager.GetBottomPane(); bottom {
+		case "":
+		case dstPane:
+			return c.manager.SwapIntoBottom(srcPane)
+		case srcPane:
+			return c.manager.SwapIntoBottom(dstPane)
+		}
+	}
+	if _, err := c.tmuxCmd("swap-pane", "-s", srcPane, "-t", dstPane); err != nil {
+		return err
+	}
+
+	for role, pane := range c.roles {
+		switch pane {
+		case srcPane:
+			c.roles[role] = dstPane
+		case dstPane:
+			c.roles[role] = srcPane
+		}
+	}
+	return nil
 }
 
 // CloseWindow closes a window
 func (c *controller) CloseWindow(window string) error {
-	_, err := tmuxCmd("kill-window", "-t", window)
+	_, err := c.tmuxCmd("kill-window", "-t", window)
 	return err
 }
 
 // FocusPane focuses a pane by role
 func (c *controller) FocusPane(role Role) error {
-	// For now, this is a no-op
-	// In a full implementation, this would map role to specific pane IDs
-	return nil
+	pane, err := c.PaneForRole(role)
+	if err != nil {
+		return err
+	}
+	_, err = c.tmuxCmd("select-pane", "-t", pane)
+	return err
 }
 
-// tmuxCmd is a helper to run tmux commands
-func tmuxCmd(args ...string) (string, error) {
-	return tmux.TmuxCmd(args...)
+// tmuxCmd is a helper to run tmux commands on the controller's server
+func (c *controller) tmuxCmd(args ...string) (string, error) {
+	return c.runner.Run(args...)
 }
@@ -0,0 +1,88 @@
+package controller_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/xunzhou/muxctl/pkg/controller"
+	"github.com/xunzhou/muxctl/pkg/tmux"
+	"github.com/xunzhou/muxctl/pkg/tmux/tmuxtest"
+)
+
+// roles returns the panes filling the three roles
+func roles(t *testing.T, c controller.Controller) []string {
+	t.Helper()
+	var panes []string
+	for _, role := range []controller.Role{controller.RoleLeft, controller.RoleCenter, controller.RoleRight} {
+		pane, err := c.PaneForRole(role)
+		if err != nil {
+			t.Fatalf("PaneForRole(%s): %v", role, err)
+		}
+		panes = append(panes, pane)
+	}
+	return panes
+}
+
+func TestTwoPaneSwapGoesThroughManager(t *testing.T) {
+	srv := tmuxtest.NewServer()
+	c := controller.New(tmux.WithRunner(srv))
+	if err := c.Init("test", controller.DefaultLayout()); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	mgr := c.GetManager()
+	for _, id := range []string{"pod-a", "pod-b"} {
+		if err := mgr.AttachResourceTerminal(id); err != nil {
+			t.Fatalf("AttachResourceTerminal %s: %v", id, err)
+		}
+	}
+	podA := mgr.GetResourcePanes()["pod-a"]
+
+	if err := c.SwapPanesByTarget(podA, string(controller.RoleCenter)); err != nil {
+		t.Fatalf("SwapPanesByTarget: %v", err)
+	}
+	if got := mgr.GetBottomPane(); got != podA {
+		t.Errorf("bottom pane = %s, want pod-a's pane %s", got, podA)
+	}
+	if got := mgr.GetActiveResource(); got != "pod-a" {
+		t.Errorf("active resource = %q, want pod-a", got)
+	}
+	if got, _ := c.PaneForRole(controller.RoleCenter); got != podA {
+		t.Errorf("center pane = %s, want %s", got, podA)
+	}
+	if got := srv.PaneIDs(srv.PaneWindow(mgr.GetTUIPane())); len(got) != 2 || got[1] != podA {
+		t.Errorf("main window panes = %v, want pod-a's pane below the TUI", got)
+	}
+}
+
+func TestThreePaneInitAdoptsColumns(t *testing.T) {
+	srv := tmuxtest.NewServer()
+	c := controller.New(tmux.WithRunner(srv))
+	if err := c.Init("test", controller.ThreePaneLayout()); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	first := roles(t, c)
+	window := srv.PaneWindow(first[0])
+	if got := srv.PaneIDs(window); len(got) != 3 {
+		t.Fatalf("panes after Init = %v, want 3", got)
+	}
+
+	// Swapping keeps roles with their columns
+	if err := c.SwapPanesByTarget(string(controller.RoleCenter), string(controller.RoleRight)); err != nil {
+		t.Fatalf("SwapPanesByTarget: %v", err)
+	}
+	swapped := roles(t, c)
+	if swapped[1] != first[2] || swapped[2] != first[1] {
+		t.Fatalf("roles after swapping center and right = %v, was %v", swapped, first)
+	}
+
+	// Init again finds the same columns instead of splitting them
+	if err := c.Init("test", controller.ThreePaneLayout()); err != nil {
+		t.Fatalf("second Init: %v", err)
+	}
+	if got := srv.PaneIDs(window); len(got) != 3 {
+		t.Errorf("panes after a second Init = %v, want 3", got)
+	}
+	if got := roles(t, c); !reflect.DeepEqual(got, swapped) {
+		t.Errorf("roles after a second Init = %v, want %v", got, swapped)
+	}
+}
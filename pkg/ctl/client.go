@@ -0,0 +1,41 @@
+package ctl
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+// dialTimeout bounds connecting to a muxctl that may have gone away
+const dialTimeout = 2 * time.Second
+
+// Do sends one request to the muxctl listening on path and returns its reply
+// A reply with Success unset is returned as an error
+func Do(path string, req *Request) (*Response, error) {
+	conn, err := net.DialTimeout("unix", path, dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("connect to muxctl (is it running in this session?): %w", err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+	if !resp.Success {
+		return &resp, fmt.Errorf("%s", resp.Error)
+	}
+	return &resp, nil
+}
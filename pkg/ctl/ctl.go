@@ -0,0 +1,95 @@
+package ctl
+
+import (
+	"fmt"
+
+	"github.com/xunzhou/muxctl/pkg/rundir"
+)
+
+// Action is a command a client asks the running muxctl to perform
+type Action string
+
+const (
+	ActionList    Action = "list"    // List resources and AI chats
+	ActionAttach  Action = "attach"  // Show a resource or AI chat in the bottom pane
+	ActionAINew   Action = "ai-new"  // Start a new AI chat
+	ActionClose   Action = "close"   // Close a resource or AI chat pane
+	ActionRename  Action = "rename"  // Title an AI chat
+	ActionSend    Action = "send"    // Send tmux keys to a resource or AI chat
+	ActionCapture Action = "capture" // Capture the contents of a resource or AI chat
+)
+
+// SocketOption is the tmux session option holding the control socket's path
+const SocketOption = "@muxctl-ctl-socket"
+
+// Item kinds reported by ActionList
+const (
+	KindResource = "resource"
+	KindAI       = "ai"
+)
+
+// Request is one command sent to the running muxctl
+type Request struct {
+	Action Action   `json:"action"`
+	ID     string   `json:"id,omitempty"`   // Resource or AI chat ID, or the pane ID showing it
+	Keys   []string `json:"keys,omitempty"` // Keys for ActionSend, in send-keys syntax
+
+	// ActionRename
+	Title string `json:"title,omitempty"` // New title ("" = untitled)
+
+	// ActionAINew
+	Backend string `json:"backend,omitempty"` // AI backend ("" = the selected one)
+	Resume  string `json:"resume,omitempty"`  // Backend session ID to resume
+}
+
+// Item describes a resource or AI chat
+type Item struct {
+	Kind   string `json:"kind"`
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Group  string `json:"group,omitempty"`
+	PaneID string `json:"pane_id,omitempty"` // Empty when no pane is open
+	Active bool   `json:"active"`            // Shown in the bottom pane
+
+	// AI chats only
+	Backend string `json:"backend,omitempty"`
+	Session string `json:"session,omitempty"` // Backend session ID, for resuming
+}
+
+// Response answers a Request
+type Response struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	ID      string `json:"id,omitempty"`      // Affected resource or AI chat
+	Items   []Item `json:"items,omitempty"`   // ActionList result
+	Content string `json:"content,omitempty"` // ActionCapture result
+}
+
+// Validate validates a request
+func (r *Request) Validate() error {
+	switch r.Action {
+	case "":
+		return fmt.Errorf("action is required")
+	case ActionList, ActionAINew:
+	case ActionAttach, ActionClose, ActionCapture, ActionRename:
+		if r.ID == "" {
+			return fmt.Errorf("id is required for %s action", r.Action)
+		}
+	case ActionSend:
+		if r.ID == "" {
+			return fmt.Errorf("id is required for send action")
+		}
+		if len(r.Keys) == 0 {
+			return fmt.Errorf("keys are required for send action")
+		}
+	default:
+		return fmt.Errorf("unknown action: %s", r.Action)
+	}
+	return nil
+}
+
+// SocketPath returns the Unix socket path for a session on the tmux server
+// listening on serverSocket
+func SocketPath(serverSocket, sessionName string) (string, error) {
+	return rundir.Path(serverSocket, sessionName, "ctl.sock")
+}
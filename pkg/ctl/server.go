@@ -0,0 +1,38 @@
+package ctl
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/xunzhou/muxctl/pkg/socket"
+)
+
+// Handler carries out validated requests
+type Handler interface {
+	HandleCtl(req *Request) *Response
+}
+
+// Listen serves requests from muxctl subcommands on a Unix socket at path
+// Each line is a Request and is answered by exactly one Response line
+func Listen(path string, handler Handler) (*socket.Server, error) {
+	return socket.Listen(path, func(line []byte) interface{} {
+		return handle(handler, line)
+	})
+}
+
+// handle decodes and validates one request line before passing it on
+func handle(handler Handler, line []byte) *Response {
+	var req Request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &Response{Error: fmt.Sprintf("invalid request: %v", err)}
+	}
+	if err := req.Validate(); err != nil {
+		return &Response{ID: req.ID, Error: err.Error()}
+	}
+
+	resp := handler.HandleCtl(&req)
+	if resp == nil {
+		return &Response{ID: req.ID, Error: "no response"}
+	}
+	return resp
+}
@@ -0,0 +1,112 @@
+package embedded
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// tmuxKeyNames maps Bubble Tea key types to tmux send-keys key names
+// Several control keys share a code with a named key (ctrl+i is Tab, ctrl+m
+// is Enter, ctrl+[ is Escape); those are sent as the named key
+var tmuxKeyNames = map[tea.KeyType]string{
+	tea.KeyEnter:     "Enter",
+	tea.KeyTab:       "Tab",
+	tea.KeyBackspace: "BSpace",
+	tea.KeyEsc:       "Escape",
+	tea.KeySpace:     "Space",
+	tea.KeyShiftTab:  "BTab",
+
+	tea.KeyUp:     "Up",
+	tea.KeyDown:   "Down",
+	tea.KeyRight:  "Right",
+	tea.KeyLeft:   "Left",
+	tea.KeyHome:   "Home",
+	tea.KeyEnd:    "End",
+	tea.KeyPgUp:   "PPage",
+	tea.KeyPgDown: "NPage",
+	tea.KeyDelete: "DC",
+	tea.KeyInsert: "IC",
+
+	tea.KeyCtrlUp:     "C-Up",
+	tea.KeyCtrlDown:   "C-Down",
+	tea.KeyCtrlRight:  "C-Right",
+	tea.KeyCtrlLeft:   "C-Left",
+	tea.KeyCtrlHome:   "C-Home",
+	tea.KeyCtrlEnd:    "C-End",
+	tea.KeyCtrlPgUp:   "C-PPage",
+	tea.KeyCtrlPgDown: "C-NPage",
+
+	tea.KeyShiftUp:    "S-Up",
+	tea.KeyShiftDown:  "S-Down",
+	tea.KeyShiftRight: "S-Right",
+	tea.KeyShiftLeft:  "S-Left",
+	tea.KeyShiftHome:  "S-Home",
+	tea.KeyShiftEnd:   "S-End",
+
+	tea.KeyCtrlShiftUp:    "C-S-Up",
+	tea.KeyCtrlShiftDown:  "C-S-Down",
+	tea.KeyCtrlShiftRight: "C-S-Right",
+	tea.KeyCtrlShiftLeft:  "C-S-Left",
+	tea.KeyCtrlShiftHome:  "C-S-Home",
+	tea.KeyCtrlShiftEnd:   "C-S-End",
+
+	tea.KeyCtrlAt:           "C-@",
+	tea.KeyCtrlBackslash:    `C-\`,
+	tea.KeyCtrlCloseBracket: "C-]",
+	tea.KeyCtrlCaret:        "C-^",
+	tea.KeyCtrlUnderscore:   "C-_",
+
+	tea.KeyF1:  "F1",
+	tea.KeyF2:  "F2",
+	tea.KeyF3:  "F3",
+	tea.KeyF4:  "F4",
+	tea.KeyF5:  "F5",
+	tea.KeyF6:  "F6",
+	tea.KeyF7:  "F7",
+	tea.KeyF8:  "F8",
+	tea.KeyF9:  "F9",
+	tea.KeyF10: "F10",
+	tea.KeyF11: "F11",
+	tea.KeyF12: "F12",
+	// tmux has no F13 and up; terminals report them as shifted F1-F8
+	tea.KeyF13: "S-F1",
+	tea.KeyF14: "S-F2",
+	tea.KeyF15: "S-F3",
+	tea.KeyF16: "S-F4",
+	tea.KeyF17: "S-F5",
+	tea.KeyF18: "S-F6",
+	tea.KeyF19: "S-F7",
+	tea.KeyF20: "S-F8",
+}
+
+// sendKeysArgs translates a key press into send-keys arguments
+// Typed text is sent literally (-l) so words such as "Enter" are not read as
+// key names; Alt is sent as the M- modifier. It returns nil for keys tmux
+// cannot send
+func sendKeysArgs(msg tea.KeyMsg) []string {
+	meta := ""
+	if msg.Alt {
+		meta = "M-"
+	}
+
+	if msg.Type == tea.KeyRunes {
+		if len(msg.Runes) == 0 {
+			return nil
+		}
+		if !msg.Alt {
+			return []string{"-l", "--", string(msg.Runes)}
+		}
+		args := []string{"--"}
+		for _, r := range msg.Runes {
+			args = append(args, meta+string(r))
+		}
+		return args
+	}
+
+	if name, ok := tmuxKeyNames[msg.Type]; ok {
+		return []string{meta + name}
+	}
+	if msg.Type >= tea.KeyCtrlA && msg.Type <= tea.KeyCtrlZ {
+		return []string{meta + "C-" + string(rune('a'+msg.Type-tea.KeyCtrlA))}
+	}
+	return nil
+}
@@ -16,13 +16,15 @@ type Session struct {
 }
 
 // NewEmbeddedSession creates a new embedded session
-func NewEmbeddedSession(name string, width, height int) (*Session, error) {
+// Options such as tmux.WithSocketName and tmux.WithTarget run it on a
+// dedicated tmux server
+func NewEmbeddedSession(name string, width, height int, opts ...tmux.Option) (*Session, error) {
 	// Check if tmux is available
-	if _, err := tmux.TmuxCmd("display-message", "-p", "#{version}"); err != nil {
+	if _, err := tmux.NewRunner(opts...).Run("display-message", "-p", "#{version}"); err != nil {
 		return nil, fmt.Errorf("tmux not available: %w", err)
 	}
 
-	mgr, err := tmux.NewManager()
+	mgr, err := tmux.NewManager(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create manager: %w", err)
 	}
@@ -111,99 +113,5 @@ func (p *ContextShellPool) Cleanup() error {
 	return nil
 }
 
-// TerminalViewport provides a view into a terminal pane
-type TerminalViewport struct {
-	manager      *tmux.Manager
-	width        int
-	height       int
-	activePaneID string
-	buffer       []byte
-}
-
-// NewTerminalViewport creates a new terminal viewport
-func NewTerminalViewport(manager *tmux.Manager, width, height int) *TerminalViewport {
-	return &TerminalViewport{
-		manager: manager,
-		width:   width,
-		height:  height,
-	}
-}
-
-// Update refreshes the viewport content
-func (v *TerminalViewport) Update() (string, error) {
-	// Capture output from the bottom pane
-	paneID := v.manager.GetBottomPane()
-	if paneID == "" {
-		return "", nil
-	}
-
-	// Capture pane content
-	output, err := tmux.TmuxCmd("capture-pane", "-t", paneID, "-p")
-	if err != nil {
-		return "", fmt.Errorf("failed to capture pane: %w", err)
-	}
-
-	return output, nil
-}
-
-// SendKeys sends keys to the active pane
-func (v *TerminalViewport) SendKeys(keys string) error {
-	paneID := v.manager.GetBottomPane()
-	if paneID == "" {
-		return fmt.Errorf("no active pane")
-	}
-
-	_, err := tmux.TmuxCmd("send-keys", "-t", paneID, keys)
-	return err
-}
-
-// SetActivePane sets the active pane for this viewport
-func (v *TerminalViewport) SetActivePane(paneID string) {
-	v.activePaneID = paneID
-}
-
-// Resize resizes the viewport
-func (v *TerminalViewport) Resize(width, height int) {
-	v.width = width
-	v.height = height
-}
-
-// SetProgram is a no-op for compatibility (Bubble Tea program management handled elsewhere)
-func (v *TerminalViewport) SetProgram(p interface{}) {
-	// No-op: program management is handled at a higher level
-}
-
-// Init initializes the viewport (returns nil Cmd for Bubble Tea compatibility)
-func (v *TerminalViewport) Init() interface{} {
-	// Return nil (valid tea.Cmd)
-	return nil
-}
-
-// SetTargetPane sets the target pane for this viewport
-func (v *TerminalViewport) SetTargetPane(paneID string) {
-	v.activePaneID = paneID
-}
-
-// HandleKey handles a key press in terminal mode
-func (v *TerminalViewport) HandleKey(keyMsg interface{}) error {
-	// Extract the key string from the message
-	// For now, just convert to string
-	return v.SendKeys(fmt.Sprintf("%v", keyMsg))
-}
-
-// View returns the current view of the terminal
-func (v *TerminalViewport) View() string {
-	output, err := v.Update()
-	if err != nil {
-		return fmt.Sprintf("Error: %v", err)
-	}
-	return output
-}
-
 // CaptureOptions provides options for capturing pane content
-type CaptureOptions struct {
-	Start int  // Start line
-	End   int  // End line
-	Lines int  // Number of lines to capture
-	Join  bool // Join wrapped lines
-}
+type CaptureOptions = tmux.CaptureOptions
@@ -0,0 +1,269 @@
+package embedded
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xunzhou/muxctl/pkg/tmux"
+)
+
+// pollInterval is how often the viewport re-captures its pane when the
+// runner cannot report pane output (anything but control mode)
+const pollInterval = 250 * time.Millisecond
+
+// OutputMsg reports that panes produced output
+// Each viewport waits for its own messages; pass every OutputMsg to all of
+// them and only the one that asked for it keeps watching
+type OutputMsg struct {
+	PaneIDs []string
+
+	viewport *TerminalViewport // Viewport whose wait produced the message
+}
+
+// pollMsg asks a viewport without output notifications to re-capture
+type pollMsg struct {
+	viewport *TerminalViewport
+}
+
+// TerminalViewport provides a view into a terminal pane
+// The content is captured with colours when the pane produces output, so
+// View is cheap and can be called on every render
+type TerminalViewport struct {
+	manager      *tmux.Manager
+	width        int
+	height       int
+	activePaneID string // Pane to show; empty follows the bottom pane
+	content      string // Last capture, trimmed to height
+	captured     bool   // content holds a capture of the current pane
+	err          error  // Error from the last capture
+
+	notifications <-chan tmux.Notification
+	unsubscribe   func()
+}
+
+// NewTerminalViewport creates a new terminal viewport
+func NewTerminalViewport(manager *tmux.Manager, width, height int) *TerminalViewport {
+	return &TerminalViewport{
+		manager: manager,
+		width:   width,
+		height:  height,
+	}
+}
+
+// targetPane returns the pane the viewport shows
+func (v *TerminalViewport) targetPane() string {
+	if v.activePaneID != "" {
+		return v.activePaneID
+	}
+	return v.manager.GetBottomPane()
+}
+
+// Update re-captures the target pane and returns its content
+func (v *TerminalViewport) Update() (string, error) {
+	paneID := v.targetPane()
+	if paneID == "" {
+		v.content, v.captured, v.err = "", true, nil
+		return "", nil
+	}
+
+	capture, err := v.manager.CapturePane(paneID, tmux.CaptureOptions{Escapes: true})
+	if err != nil {
+		// Keep the error on screen until the pane changes instead of
+		// retrying on every render
+		v.content, v.captured, v.err = "", true, err
+		return "", err
+	}
+
+	v.content, v.captured, v.err = v.fit(capture.Content), true, nil
+	return v.content, nil
+}
+
+// fit trims or pads captured content to the viewport height
+// Lines are not cut to the width since escape sequences make their visible
+// length unknown; resize-pane keeps tmux from producing longer lines
+func (v *TerminalViewport) fit(content string) string {
+	if v.height <= 0 {
+		return content
+	}
+	lines := strings.Split(content, "\n")
+	if len(lines) > v.height {
+		lines = lines[len(lines)-v.height:]
+	}
+	for len(lines) < v.height {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SendKeys sends keys to the target pane using send-keys syntax
+func (v *TerminalViewport) SendKeys(keys ...string) error {
+	paneID := v.targetPane()
+	if paneID == "" {
+		return fmt.Errorf("no active pane")
+	}
+	return v.manager.SendKeys(paneID, keys...)
+}
+
+// SetActivePane sets the active pane for this viewport
+func (v *TerminalViewport) SetActivePane(paneID string) {
+	v.SetTargetPane(paneID)
+}
+
+// SetTargetPane sets the pane this viewport shows and sizes it to fit
+// An empty pane ID follows the bottom pane again
+func (v *TerminalViewport) SetTargetPane(paneID string) {
+	if paneID == v.activePaneID {
+		return
+	}
+	v.activePaneID = paneID
+	v.captured = false
+	v.resizePane()
+}
+
+// Resize resizes the viewport and the pane it shows
+func (v *TerminalViewport) Resize(width, height int) {
+	if width == v.width && height == v.height {
+		return
+	}
+	v.width = width
+	v.height = height
+	v.captured = false
+	v.resizePane()
+}
+
+// resizePane makes the target pane match the viewport size
+func (v *TerminalViewport) resizePane() {
+	paneID := v.targetPane()
+	if paneID == "" || v.width <= 0 || v.height <= 0 {
+		return
+	}
+	// tmux clamps the size to the window; the capture shows what it chose
+	v.manager.TmuxCmd("resize-pane", "-t", paneID,
+		"-x", strconv.Itoa(v.width), "-y", strconv.Itoa(v.height))
+}
+
+// SetProgram is a no-op for compatibility (Bubble Tea program management handled elsewhere)
+func (v *TerminalViewport) SetProgram(p interface{}) {
+	// No-op: program management is handled at a higher level
+}
+
+// Init starts watching the target pane for output
+// With control mode the viewport refreshes on %output notifications;
+// otherwise it falls back to polling
+func (v *TerminalViewport) Init() tea.Cmd {
+	if v.unsubscribe == nil {
+		v.notifications, v.unsubscribe = v.manager.Subscribe()
+	}
+	v.resizePane()
+	return v.wait()
+}
+
+// Close stops watching for pane output
+func (v *TerminalViewport) Close() {
+	if v.unsubscribe != nil {
+		v.unsubscribe()
+		v.unsubscribe = nil
+		v.notifications = nil
+	}
+}
+
+// wait returns a command that delivers the next refresh message
+func (v *TerminalViewport) wait() tea.Cmd {
+	if v.notifications == nil {
+		return tea.Tick(pollInterval, func(time.Time) tea.Msg {
+			return pollMsg{viewport: v}
+		})
+	}
+	return v.waitForOutput()
+}
+
+// waitForOutput blocks until a pane produces output
+// Notifications that are already queued are folded into the same message so
+// a burst of output causes a single refresh
+func (v *TerminalViewport) waitForOutput() tea.Cmd {
+	ch := v.notifications
+	return func() tea.Msg {
+		msg := OutputMsg{viewport: v}
+		add := func(n tmux.Notification) {
+			if n.Name != tmux.NotifyOutput || len(n.Args) == 0 {
+				return
+			}
+			for _, id := range msg.PaneIDs {
+				if id == n.Args[0] {
+					return
+				}
+			}
+			msg.PaneIDs = append(msg.PaneIDs, n.Args[0])
+		}
+
+		for n := range ch {
+			add(n)
+			if len(msg.PaneIDs) == 0 {
+				continue
+			}
+			for {
+				select {
+				case n, ok := <-ch:
+					if !ok {
+						return msg
+					}
+					add(n)
+					continue
+				default:
+				}
+				return msg
+			}
+		}
+		return nil
+	}
+}
+
+// HandleMsg refreshes the viewport on output from its pane and keeps
+// watching; other messages are ignored and return nil
+func (v *TerminalViewport) HandleMsg(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case OutputMsg:
+		if msg.viewport != v {
+			return nil
+		}
+		target := v.targetPane()
+		for _, paneID := range msg.PaneIDs {
+			if paneID == target {
+				v.captured = false
+			}
+		}
+		return v.wait()
+	case pollMsg:
+		if msg.viewport != v {
+			return nil
+		}
+		v.captured = false
+		return v.wait()
+	}
+	return nil
+}
+
+// HandleKey sends a key press to the target pane
+func (v *TerminalViewport) HandleKey(msg tea.KeyMsg) error {
+	args := sendKeysArgs(msg)
+	if args == nil {
+		return nil
+	}
+	return v.SendKeys(args...)
+}
+
+// View returns the current view of the terminal
+// The pane is only captured again after it produced output or the viewport
+// changed size or target
+func (v *TerminalViewport) View() string {
+	if !v.captured {
+		v.Update()
+	}
+	if v.err != nil {
+		return fmt.Sprintf("Error: %v", v.err)
+	}
+	return v.content
+}
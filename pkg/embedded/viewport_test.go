@@ -0,0 +1,38 @@
+package embedded
+
+import (
+	"testing"
+
+	"github.com/xunzhou/muxctl/pkg/tmux"
+	"github.com/xunzhou/muxctl/pkg/tmux/tmuxtest"
+)
+
+func TestOutputMsgRearmsOnlyItsViewport(t *testing.T) {
+	srv := tmuxtest.NewServer()
+	mgr, err := tmux.NewManager(tmux.WithRunner(srv))
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if err := mgr.Setup(); err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	pane := mgr.GetBottomPane()
+	a := NewTerminalViewport(mgr, 80, 24)
+	b := NewTerminalViewport(mgr, 80, 24)
+	a.View()
+	b.View()
+
+	msg := OutputMsg{PaneIDs: []string{pane}, viewport: a}
+	if cmd := b.HandleMsg(msg); cmd != nil {
+		t.Errorf("viewport b kept waiting on a's message")
+	}
+	if !b.captured {
+		t.Errorf("viewport b was refreshed by a's message")
+	}
+	if cmd := a.HandleMsg(msg); cmd == nil {
+		t.Errorf("viewport a stopped waiting for output")
+	}
+	if a.captured {
+		t.Errorf("viewport a was not refreshed by output from its pane")
+	}
+}
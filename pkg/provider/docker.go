@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/xunzhou/muxctl/pkg/config"
+)
+
+// Docker discovers running containers with `docker ps`
+type Docker struct {
+	Shell string // Shell to exec in the container (default sh)
+}
+
+// Name returns "docker"
+func (d *Docker) Name() string {
+	return config.ProviderDocker
+}
+
+// Resources lists running containers, each connecting with `docker exec -it`
+func (d *Docker) Resources(ctx context.Context) ([]config.Resource, error) {
+	// {{json .}} prints one object per line and, unlike "--format json",
+	// also works with older docker releases
+	output, err := run(ctx, "docker", "ps", "--format", "{{json .}}")
+	if err != nil {
+		return nil, err
+	}
+	return d.parseContainers(output)
+}
+
+// container is the subset of `docker ps` JSON output we use
+type container struct {
+	ID    string `json:"ID"`
+	Names string `json:"Names"`
+	Image string `json:"Image"`
+}
+
+// parseContainers converts line-delimited docker JSON output into resources
+func (d *Docker) parseContainers(data []byte) ([]config.Resource, error) {
+	shell := d.Shell
+	if shell == "" {
+		shell = "sh"
+	}
+
+	var resources []config.Resource
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var c container
+		if err := json.Unmarshal([]byte(line), &c); err != nil {
+			return nil, fmt.Errorf("parse docker output: %w", err)
+		}
+
+		// A container may have several comma-separated names; any will do
+		name, _, _ := strings.Cut(c.Names, ",")
+		if name == "" {
+			name = c.ID
+		}
+		resources = append(resources, config.Resource{
+			ID:      "docker/" + name,
+			Name:    name,
+			Group:   "docker",
+			Command: shellJoin("docker", "exec", "-it", name, shell),
+			Prompt:  fmt.Sprintf("[%s] $ ", name),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read docker output: %w", err)
+	}
+	return resources, nil
+}
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/xunzhou/muxctl/pkg/config"
+)
+
+// Kubernetes discovers running pods with `kubectl get pods -o json`
+type Kubernetes struct {
+	Context       string // kubeconfig context ("" = current)
+	Namespace     string // Namespace ("" = the context's namespace)
+	AllNamespaces bool   // List pods in every namespace
+	Shell         string // Shell to exec in the pod (default sh)
+}
+
+// Name returns "kubernetes"
+func (k *Kubernetes) Name() string {
+	return config.ProviderKubernetes
+}
+
+// Resources lists running pods, each connecting with `kubectl exec -it`
+func (k *Kubernetes) Resources(ctx context.Context) ([]config.Resource, error) {
+	args := append(k.contextArgs(), "get", "pods", "-o", "json")
+	if k.AllNamespaces {
+		args = append(args, "--all-namespaces")
+	} else if k.Namespace != "" {
+		args = append(args, "--namespace", k.Namespace)
+	}
+
+	output, err := run(ctx, "kubectl", args...)
+	if err != nil {
+		return nil, err
+	}
+	return k.parsePods(output)
+}
+
+// contextArgs returns the --context flag, if a context is configured
+func (k *Kubernetes) contextArgs() []string {
+	if k.Context == "" {
+		return nil
+	}
+	return []string{"--context", k.Context}
+}
+
+// podList is the subset of `kubectl get pods -o json` output we use
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Status struct {
+			Phase string `json:"phase"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// parsePods converts kubectl JSON output into resources
+func (k *Kubernetes) parsePods(data []byte) ([]config.Resource, error) {
+	var pods podList
+	if err := json.Unmarshal(data, &pods); err != nil {
+		return nil, fmt.Errorf("parse kubectl output: %w", err)
+	}
+
+	shell := k.Shell
+	if shell == "" {
+		shell = "sh"
+	}
+
+	var resources []config.Resource
+	for _, pod := range pods.Items {
+		// Only running pods can be exec'd into
+		if pod.Status.Phase != "Running" {
+			continue
+		}
+		name, namespace := pod.Metadata.Name, pod.Metadata.Namespace
+
+		args := append([]string{"kubectl"}, k.contextArgs()...)
+		args = append(args, "exec", "-it", "--namespace", namespace, name, "--", shell)
+		resources = append(resources, config.Resource{
+			ID:      fmt.Sprintf("k8s/%s/%s", namespace, name),
+			Name:    name,
+			Group:   "kubernetes/" + namespace,
+			Command: shellJoin(args...),
+			Prompt:  fmt.Sprintf("[%s] $ ", name),
+		})
+	}
+	return resources, nil
+}
@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/xunzhou/muxctl/pkg/config"
+)
+
+// ResourceProvider discovers resources dynamically
+// Each discovered resource carries the connect command that
+// AttachResourceTerminal runs in the resource window
+type ResourceProvider interface {
+	// Name identifies the provider in messages and resource groups
+	Name() string
+
+	// Resources lists the resources currently available
+	Resources(ctx context.Context) ([]config.Resource, error)
+}
+
+// New creates the provider described by cfg
+func New(cfg config.Provider) (ResourceProvider, error) {
+	switch cfg.Type {
+	case config.ProviderKubernetes:
+		return &Kubernetes{
+			Context:       cfg.Context,
+			Namespace:     cfg.Namespace,
+			AllNamespaces: cfg.AllNamespaces,
+			Shell:         cfg.Shell,
+		}, nil
+	case config.ProviderDocker:
+		return &Docker{Shell: cfg.Shell}, nil
+	case config.ProviderSSH:
+		return &SSHConfig{Path: cfg.Path}, nil
+	}
+	return nil, fmt.Errorf("unknown provider type %q", cfg.Type)
+}
+
+// run executes a command found on PATH and returns its stdout
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// shellJoin quotes args into a single shell command line
+func shellJoin(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && strings.IndexFunc(arg, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+				strings.ContainsRune("-_./:=@,+", r))
+		}) < 0 {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
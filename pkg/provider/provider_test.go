@@ -0,0 +1,202 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xunzhou/muxctl/pkg/config"
+)
+
+// fakeKubectl stands in for kubectl: it logs its arguments, rejects contexts
+// missing from $KUBECONFIG like kubectl does and prints testdata/pods.json
+const fakeKubectl = `#!/bin/sh
+echo "$@" > "$FAKE_ARGS"
+while [ $# -gt 0 ]; do
+	if [ "$1" = --context ]; then
+		if ! grep -A2 "^- name: $2\$" "$KUBECONFIG" | grep -q "context:"; then
+			echo "error: context \"$2\" does not exist" >&2
+			exit 1
+		fi
+	fi
+	shift
+done
+cat "$TESTDATA/pods.json"
+`
+
+// fakeDocker stands in for docker and prints testdata/docker-ps.jsonl
+const fakeDocker = `#!/bin/sh
+echo "$@" > "$FAKE_ARGS"
+cat "$TESTDATA/docker-ps.jsonl"
+`
+
+// fakePath puts the fake binaries first on PATH and returns the file they
+// log their arguments to
+func fakePath(t *testing.T) string {
+	t.Helper()
+	bin := t.TempDir()
+	for name, script := range map[string]string{"kubectl": fakeKubectl, "docker": fakeDocker} {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testdata, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	argsFile := filepath.Join(t.TempDir(), "args")
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("TESTDATA", testdata)
+	t.Setenv("KUBECONFIG", filepath.Join(testdata, "kubeconfig"))
+	t.Setenv("HOME", filepath.Join(testdata, "home"))
+	t.Setenv("FAKE_ARGS", argsFile)
+	return argsFile
+}
+
+// ids returns the resources' IDs and commands as "id: command"
+func ids(resources []config.Resource) []string {
+	var out []string
+	for _, r := range resources {
+		out = append(out, r.ID+": "+r.Command)
+	}
+	return out
+}
+
+func TestProviders(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.Provider
+		wantArgs string
+		want     []string
+		wantErr  string
+	}{
+		{
+			name:     "kubernetes current context",
+			cfg:      config.Provider{Type: config.ProviderKubernetes},
+			wantArgs: "get pods -o json",
+			want: []string{
+				"k8s/web/api-7d9f: kubectl exec -it --namespace web api-7d9f -- sh",
+				"k8s/kube-system/coredns-5c8: kubectl exec -it --namespace kube-system coredns-5c8 -- sh",
+			},
+		},
+		{
+			name:     "kubernetes context and namespace",
+			cfg:      config.Provider{Type: config.ProviderKubernetes, Context: "prod", Namespace: "web", Shell: "bash"},
+			wantArgs: "--context prod get pods -o json --namespace web",
+			want: []string{
+				"k8s/web/api-7d9f: kubectl --context prod exec -it --namespace web api-7d9f -- bash",
+				"k8s/kube-system/coredns-5c8: kubectl --context prod exec -it --namespace kube-system coredns-5c8 -- bash",
+			},
+		},
+		{
+			name:     "kubernetes all namespaces",
+			cfg:      config.Provider{Type: config.ProviderKubernetes, Namespace: "web", AllNamespaces: true},
+			wantArgs: "get pods -o json --all-namespaces",
+			want: []string{
+				"k8s/web/api-7d9f: kubectl exec -it --namespace web api-7d9f -- sh",
+				"k8s/kube-system/coredns-5c8: kubectl exec -it --namespace kube-system coredns-5c8 -- sh",
+			},
+		},
+		{
+			name:    "kubernetes unknown context",
+			cfg:     config.Provider{Type: config.ProviderKubernetes, Context: "staging"},
+			wantErr: `context "staging" does not exist`,
+		},
+		{
+			name:     "docker",
+			cfg:      config.Provider{Type: config.ProviderDocker},
+			wantArgs: "ps --format {{json .}}",
+			want: []string{
+				"docker/web: docker exec -it web sh",
+				"docker/9c8d7e: docker exec -it 9c8d7e sh",
+			},
+		},
+		{
+			name: "ssh",
+			cfg:  config.Provider{Type: config.ProviderSSH, Path: "testdata/ssh_config"},
+			want: []string{
+				"ssh/web: ssh web",
+				"ssh/db: ssh db",
+				"ssh/db-replica: ssh db-replica",
+				"ssh/bastion: ssh bastion",
+				"ssh/staging: ssh staging",
+			},
+		},
+		{
+			name: "ssh missing config",
+			cfg:  config.Provider{Type: config.ProviderSSH, Path: "testdata/missing"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			argsFile := fakePath(t)
+			p, err := New(tt.cfg)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			resources, err := p.Resources(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resources error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resources: %v", err)
+			}
+			if got := ids(resources); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resources =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if tt.wantArgs != "" {
+				args, err := os.ReadFile(argsFile)
+				if err != nil {
+					t.Fatalf("provider did not run its command: %v", err)
+				}
+				if got := strings.TrimSpace(string(args)); got != tt.wantArgs {
+					t.Errorf("ran with %q, want %q", got, tt.wantArgs)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSSHConfigSeparators(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{"space", "Host alpha", []string{"alpha"}},
+		{"tab", "Host\talpha", []string{"alpha"}},
+		{"tabs and spaces", "  Host \t alpha\tbeta ", []string{"alpha", "beta"}},
+		{"equals", "Host=alpha", []string{"alpha"}},
+		{"spaced equals", "Host = alpha", []string{"alpha"}},
+		{"keyword case", "HOST\talpha", []string{"alpha"}},
+		{"keyword only", "Host", nil},
+		{"equals only", "=", nil},
+		{"no keyword", "= alpha", nil},
+		{"equals in argument", "Host alpha=beta", []string{"alpha=beta"}},
+		{"patterns", "Host\t*.corp !alpha beta?", nil},
+		{"other keyword", "HostName\talpha.example.com", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config")
+			if err := os.WriteFile(path, []byte(tt.line+"\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseSSHConfig(path, filepath.Dir(path), make(map[string]bool))
+			if err != nil {
+				t.Fatalf("parseSSHConfig: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hosts = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xunzhou/muxctl/pkg/config"
+)
+
+// SSHConfig discovers hosts declared in an OpenSSH client config file
+type SSHConfig struct {
+	Path string // Config file (default ~/.ssh/config)
+}
+
+// Name returns "ssh"
+func (s *SSHConfig) Name() string {
+	return config.ProviderSSH
+}
+
+// Resources lists concrete Host entries, each connecting with `ssh host`
+// Wildcard and negated patterns are skipped since they name no single host
+func (s *SSHConfig) Resources(ctx context.Context) ([]config.Resource, error) {
+	path := s.Path
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("find home directory: %w", err)
+		}
+		path = filepath.Join(home, ".ssh", "config")
+	}
+
+	hosts, err := parseSSHConfig(path, includeDir(path), make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []config.Resource
+	seen := make(map[string]bool)
+	for _, host := range hosts {
+		if seen[host] {
+			continue
+		}
+		seen[host] = true
+		resources = append(resources, config.Resource{
+			ID:      "ssh/" + host,
+			Name:    host,
+			Group:   "ssh",
+			Command: shellJoin("ssh", host),
+		})
+	}
+	return resources, nil
+}
+
+// includeDir returns the directory relative Include paths are resolved
+// against, which OpenSSH takes from the kind of config rather than from the
+// including file: /etc/ssh for the system config, ~/.ssh for any other
+func includeDir(path string) string {
+	if abs, err := filepath.Abs(path); err == nil && strings.HasPrefix(abs, "/etc/ssh/") {
+		return "/etc/ssh"
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Dir(path)
+	}
+	return filepath.Join(home, ".ssh")
+}
+
+// parseSSHConfig returns the host names declared in path, following Include
+// directives relative to dir; visited guards against include cycles
+func parseSSHConfig(path, dir string, visited map[string]bool) ([]string, error) {
+	if visited[path] {
+		return nil, nil
+	}
+	visited[path] = true
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open ssh config: %w", err)
+	}
+	defer f.Close()
+
+	var hosts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		keyword, fields := splitSSHKeyword(line)
+		switch strings.ToLower(keyword) {
+		case "host":
+			for _, pattern := range fields {
+				if !strings.ContainsAny(pattern, "*?!") {
+					hosts = append(hosts, pattern)
+				}
+			}
+		case "include":
+			for _, pattern := range fields {
+				included, err := includeSSHConfig(dir, pattern, visited)
+				if err != nil {
+					return nil, err
+				}
+				hosts = append(hosts, included...)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read ssh config: %w", err)
+	}
+	return hosts, nil
+}
+
+// splitSSHKeyword splits a config line into its keyword and arguments
+// Keywords are case-insensitive and separated from the arguments by spaces,
+// tabs or one "=" with optional whitespace; a line with no keyword, such as
+// "=", yields ""
+func splitSSHKeyword(line string) (string, []string) {
+	i := strings.IndexAny(line, " \t=")
+	if i < 0 {
+		return line, nil
+	}
+	rest := strings.TrimLeft(line[i:], " \t")
+	rest = strings.TrimPrefix(rest, "=")
+	return line[:i], strings.Fields(rest)
+}
+
+// includeSSHConfig resolves an Include pattern relative to dir and parses
+// every match
+func includeSSHConfig(dir, pattern string, visited map[string]bool) ([]string, error) {
+	if strings.HasPrefix(pattern, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			pattern = filepath.Join(home, pattern[2:])
+		}
+	} else if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("ssh config include %q: %w", pattern, err)
+	}
+
+	var hosts []string
+	for _, match := range matches {
+		included, err := parseSSHConfig(match, dir, visited)
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, included...)
+	}
+	return hosts, nil
+}
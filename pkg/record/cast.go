@@ -0,0 +1,45 @@
+package record
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// asciinema cast format, see https://docs.asciinema.org/manual/asciicast/v2/
+const (
+	castVersion = 2
+	castOutput  = "o" // Output event type
+)
+
+// castHeader is the first line of an asciinema v2 cast
+type castHeader struct {
+	Version   int    `json:"version"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+	Title     string `json:"title,omitempty"`
+}
+
+// WriteCast writes a recording as an asciinema v2 cast
+func WriteCast(w io.Writer, rec *Recording) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	header := castHeader{
+		Version:   castVersion,
+		Width:     rec.Width,
+		Height:    rec.Height,
+		Timestamp: rec.Timestamp,
+		Title:     rec.Resource,
+	}
+	if err := enc.Encode(header); err != nil {
+		return fmt.Errorf("write cast header: %w", err)
+	}
+	for _, ev := range rec.Events {
+		if err := enc.Encode([]interface{}{ev.Time, castOutput, ev.Data}); err != nil {
+			return fmt.Errorf("write cast event: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Header is the first line of a recording log
+type Header struct {
+	Version   int    `json:"version"` // 1 for muxctl logs, 2 for asciinema casts
+	Resource  string `json:"resource,omitempty"`
+	Width     int    `json:"width"`     // Pane width when recording started
+	Height    int    `json:"height"`    // Pane height when recording started
+	Timestamp int64  `json:"timestamp"` // Unix time recording started
+}
+
+// Event is a chunk of pane output
+type Event struct {
+	Time float64 // Seconds since the recording started
+	Data string
+}
+
+// Recording is a parsed recording log or asciinema cast
+type Recording struct {
+	Header
+	Events []Event
+}
+
+// Duration returns the time of the last event
+func (r *Recording) Duration() time.Duration {
+	if len(r.Events) == 0 {
+		return 0
+	}
+	return time.Duration(r.Events[len(r.Events)-1].Time * float64(time.Second))
+}
+
+// logVersion marks muxctl's own log format: the header followed by one
+// [time, data] array per line
+const logVersion = 1
+
+// Path returns a new log file path for a resource in dir, creating dir
+// Resource IDs such as "default/api-0" are flattened into the file name
+func Path(dir, resourceID string, t time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("create recording directory: %w", err)
+	}
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == os.PathSeparator || r < ' ' {
+			return '_'
+		}
+		return r
+	}, resourceID)
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.log", name, t.Format("20060102-150405"))), nil
+}
+
+// maxPending is how much output Record holds back for the filter, an
+// unterminated line or an unfinished PEM block, before writing it anyway
+const maxPending = 64 * 1024
+
+// PEM block delimiters; a filter can only redact a block it sees whole
+var (
+	pemBegin = []byte("-----BEGIN ")
+	pemEnd   = []byte("-----END ")
+)
+
+// Record writes a log header followed by everything read from r, each chunk
+// stamped with the time since recording started, until r is closed
+// It is what pipe-pane runs: r is the pane output. A non-nil filter, e.g.
+// secret redaction, sees whole lines and whole PEM blocks, so output is
+// written line by line
+func Record(r io.Reader, w io.Writer, h Header, filter func(string) string) error {
+	start := time.Now()
+	h.Version = logVersion
+	h.Timestamp = start.Unix()
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(h); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+	write := func(data []byte) error {
+		text := string(data)
+		if filter != nil {
+			text = filter(text)
+		}
+		elapsed := time.Since(start).Seconds()
+		if err := enc.Encode([]interface{}{elapsed, text}); err != nil {
+			return fmt.Errorf("write event: %w", err)
+		}
+		return nil
+	}
+
+	buf := make([]byte, 32*1024)
+	var pending []byte
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			data := append(pending, buf[:n]...)
+			cut := len(data)
+			if filter != nil {
+				cut = bytes.LastIndexByte(data, '\n') + 1
+				if i := openBlock(data[:cut]); i >= 0 {
+					cut = i
+				}
+				if cut == 0 && len(data) >= maxPending {
+					cut = len(data)
+				}
+			}
+			// Hold back a UTF-8 sequence split across reads so it is not
+			// mangled into U+FFFD by the JSON encoder
+			cut = completePrefix(data[:cut])
+			if cut > 0 {
+				if werr := write(data[:cut]); werr != nil {
+					return werr
+				}
+			}
+			pending = append([]byte(nil), data[cut:]...)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read output: %w", err)
+		}
+	}
+
+	if len(pending) > 0 {
+		return write(pending)
+	}
+	return nil
+}
+
+// openBlock returns the offset of the line starting a PEM block that data
+// does not finish, or -1
+func openBlock(data []byte) int {
+	i := bytes.LastIndex(data, pemBegin)
+	if i < 0 || bytes.Contains(data[i:], pemEnd) {
+		return -1
+	}
+	return bytes.LastIndexByte(data[:i], '\n') + 1
+}
+
+// completePrefix returns the length of data without a trailing incomplete
+// UTF-8 sequence
+func completePrefix(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
+
+// Load reads a muxctl recording log or an asciinema v2 cast
+// Cast events other than output ("o"), such as input or markers, are skipped
+func Load(path string) (*Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open recording: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("read recording: %w", err)
+		}
+		return nil, fmt.Errorf("%s: empty recording", path)
+	}
+
+	rec := &Recording{}
+	var title struct {
+		Title string `json:"title"`
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &rec.Header); err != nil {
+		return nil, fmt.Errorf("%s: bad header: %w", path, err)
+	}
+	json.Unmarshal(scanner.Bytes(), &title)
+	switch rec.Version {
+	case logVersion:
+	case castVersion:
+		if rec.Resource == "" {
+			rec.Resource = title.Title
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported recording version %d", path, rec.Version)
+	}
+
+	line := 1
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var fields []json.RawMessage
+		if err := json.Unmarshal(scanner.Bytes(), &fields); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+
+		var ev Event
+		var kind string
+		switch {
+		case rec.Version == logVersion && len(fields) == 2:
+			err = unmarshalAll(fields, &ev.Time, &ev.Data)
+			kind = castOutput
+		case rec.Version == castVersion && len(fields) == 3:
+			err = unmarshalAll(fields, &ev.Time, &kind, &ev.Data)
+		default:
+			err = fmt.Errorf("unexpected event with %d fields", len(fields))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if kind == castOutput {
+			rec.Events = append(rec.Events, ev)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read recording: %w", err)
+	}
+	return rec, nil
+}
+
+// unmarshalAll decodes each field into the matching destination
+func unmarshalAll(fields []json.RawMessage, dsts ...interface{}) error {
+	for i, dst := range dsts {
+		if err := json.Unmarshal(fields[i], dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
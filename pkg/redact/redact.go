@@ -0,0 +1,156 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Rule detects one kind of secret
+// If the pattern has a group named "secret", only that group is replaced, so
+// surrounding context such as "Authorization: Bearer" stays readable
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// Builtin rules
+var (
+	BearerToken = Rule{
+		Name:    "bearer-token",
+		Pattern: regexp.MustCompile(`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9\-._~+/]{8,}=*)`),
+	}
+	PEMPrivateKey = Rule{
+		Name:    "pem-private-key",
+		Pattern: regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----(?s:.*?)-----END [A-Z0-9 ]*PRIVATE KEY-----`),
+	}
+	AWSAccessKeyID = Rule{
+		Name:    "aws-access-key-id",
+		Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|AIDA|AROA)[0-9A-Z]{16}\b`),
+	}
+	AWSSecretAccessKey = Rule{
+		Name:    "aws-secret-access-key",
+		Pattern: regexp.MustCompile(`(?i)\baws_?secret_?access_?key\b["']?\s*[:=]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})`),
+	}
+	JWT = Rule{
+		Name:    "jwt",
+		Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]+`),
+	}
+	KubeconfigClientKey = Rule{
+		Name:    "kubeconfig-client-key",
+		Pattern: regexp.MustCompile(`\bclient-key-data:\s*["']?(?P<secret>[A-Za-z0-9+/=]{16,})`),
+	}
+)
+
+// Builtin returns the rules a Redactor uses by default
+// PEM blocks come first so a key is reported once rather than line by line
+func Builtin() []Rule {
+	return []Rule{PEMPrivateKey, KubeconfigClientKey, JWT, BearerToken, AWSAccessKeyID, AWSSecretAccessKey}
+}
+
+// Compile creates a rule from a regular expression
+// Patterns matching empty text, such as "x*", are rejected: they would match
+// between every two characters
+func Compile(name, pattern string) (Rule, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Rule{}, fmt.Errorf("redaction rule %s: %w", name, err)
+	}
+	if re.MatchString("") {
+		return Rule{}, fmt.Errorf("redaction rule %s: pattern %q matches empty text", name, pattern)
+	}
+	return Rule{Name: name, Pattern: re}, nil
+}
+
+// Match is a secret found in text
+type Match struct {
+	Rule  string
+	Start int // Byte offsets of the replaced text
+	End   int
+	Text  string
+}
+
+// Redactor replaces secrets in text
+type Redactor struct {
+	rules []Rule
+}
+
+// New creates a Redactor applying rules in order
+func New(rules ...Rule) *Redactor {
+	return &Redactor{rules: rules}
+}
+
+// Default creates a Redactor with the builtin rules
+func Default() *Redactor {
+	return New(Builtin()...)
+}
+
+// Rules returns the rules the Redactor applies
+func (r *Redactor) Rules() []Rule {
+	return append([]Rule(nil), r.rules...)
+}
+
+// Redact returns s with every secret Find reports replaced by
+// "[REDACTED:<rule>]"
+func (r *Redactor) Redact(s string) string {
+	matches := r.Find(s)
+	if len(matches) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(s[last:m.Start])
+		fmt.Fprintf(&b, "[REDACTED:%s]", m.Rule)
+		last = m.End
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// Find reports what Redact would replace in s, in order of position
+// A secret matched by an earlier rule is not reported again by a later one
+func (r *Redactor) Find(s string) []Match {
+	if r == nil {
+		return nil
+	}
+	var found []Match
+	for _, rule := range r.rules {
+		for _, span := range rule.spans(s) {
+			overlaps := false
+			for _, m := range found {
+				if span[0] < m.End && m.Start < span[1] {
+					overlaps = true
+					break
+				}
+			}
+			if !overlaps {
+				found = append(found, Match{Rule: rule.Name, Start: span[0], End: span[1], Text: s[span[0]:span[1]]})
+			}
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Start < found[j].Start
+	})
+	return found
+}
+
+// spans returns the byte ranges the rule replaces in s
+// Empty matches, say of an empty "secret" group, hide nothing and are skipped
+func (rule Rule) spans(s string) [][2]int {
+	group := rule.Pattern.SubexpIndex("secret")
+	var spans [][2]int
+	for _, match := range rule.Pattern.FindAllStringSubmatchIndex(s, -1) {
+		start, end := match[0], match[1]
+		if group > 0 && match[2*group] >= 0 {
+			start, end = match[2*group], match[2*group+1]
+		}
+		if start == end {
+			continue
+		}
+		spans = append(spans, [2]int{start, end})
+	}
+	return spans
+}
@@ -0,0 +1,79 @@
+// Package rundir locates the private directory holding muxctl's sockets and
+// FIFOs
+package rundir
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// maxSessionLen bounds the session part of a file name so socket paths stay
+// well below the 108 byte limit of sun_path
+const maxSessionLen = 32
+
+// Dir returns the private runtime directory, creating it if needed
+// It is $XDG_RUNTIME_DIR/muxctl, or muxctl-<uid> in the temp directory when
+// XDG_RUNTIME_DIR is unset; either way it must be a real directory owned by
+// the user and closed to everyone else
+func Dir() (string, error) {
+	var dir string
+	if xdg := os.Getenv("XDG_RUNTIME_DIR"); xdg != "" {
+		dir = filepath.Join(xdg, "muxctl")
+	} else {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("muxctl-%d", os.Getuid()))
+	}
+
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return "", fmt.Errorf("create runtime dir: %w", err)
+	}
+
+	// Lstat so a symlink planted by another user is rejected, not followed
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return "", fmt.Errorf("stat runtime dir: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("runtime dir %s is not a directory", dir)
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return "", fmt.Errorf("runtime dir %s is owned by uid %d", dir, st.Uid)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		if err := os.Chmod(dir, 0700); err != nil {
+			return "", fmt.Errorf("restrict runtime dir: %w", err)
+		}
+	}
+	return dir, nil
+}
+
+// Path returns the path of a file in Dir for a session on a tmux server
+// serverSocket is the server's socket path (#{socket_path}); it is hashed
+// into the name with the session name so equally named sessions on different
+// servers, and session names that look alike once made safe, don't collide
+// The session name is reduced to characters that need no quoting in shell
+// commands or tmux formats
+func Path(serverSocket, sessionName, suffix string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(serverSocket + "\x00" + sessionName))
+
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, sessionName)
+	if len(name) > maxSessionLen {
+		name = name[:maxSessionLen]
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("%s-%08x-%s", name, h.Sum32(), suffix)), nil
+}
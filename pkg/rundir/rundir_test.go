@@ -0,0 +1,86 @@
+package rundir
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDirIsPrivate(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", xdg)
+
+	dir, err := Dir()
+	if err != nil {
+		t.Fatalf("Dir: %v", err)
+	}
+	if dir != filepath.Join(xdg, "muxctl") {
+		t.Errorf("Dir = %s, want muxctl in XDG_RUNTIME_DIR", dir)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		t.Errorf("permissions = %o, want 700", perm)
+	}
+
+	// A directory opened up by someone else is closed again
+	if err := os.Chmod(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Dir(); err != nil {
+		t.Fatalf("Dir: %v", err)
+	}
+	if info, _ := os.Stat(dir); info.Mode().Perm() != 0700 {
+		t.Errorf("permissions = %o after Dir, want 700", info.Mode().Perm())
+	}
+}
+
+func TestDirRejectsSymlink(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", xdg)
+	if err := os.Symlink(t.TempDir(), filepath.Join(xdg, "muxctl")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Dir(); err == nil {
+		t.Errorf("Dir accepted a symlink")
+	}
+}
+
+func TestPath(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	a, err := Path("/tmp/tmux-1000/default", "main", "ctl.sock")
+	if err != nil {
+		t.Fatalf("Path: %v", err)
+	}
+	b, _ := Path("/tmp/tmux-1000/other", "main", "ctl.sock")
+	if a == b {
+		t.Errorf("sessions on different servers share %s", a)
+	}
+	if again, _ := Path("/tmp/tmux-1000/default", "main", "ctl.sock"); again != a {
+		t.Errorf("Path is not stable: %s then %s", a, again)
+	}
+	if !strings.HasPrefix(filepath.Base(a), "main-") || !strings.HasSuffix(a, "-ctl.sock") {
+		t.Errorf("Path = %s, want main-<hash>-ctl.sock", a)
+	}
+
+	odd, _ := Path("/tmp/tmux-1000/default", "../"+strings.Repeat("x", 100), "ai.sock")
+	if filepath.Dir(odd) != filepath.Dir(a) {
+		t.Errorf("session name escaped the runtime dir: %s", odd)
+	}
+	if len(filepath.Base(odd)) > maxSessionLen+20 {
+		t.Errorf("long session name was not shortened: %s", odd)
+	}
+
+	quoted, _ := Path("/tmp/tmux-1000/default", `it's "#{main}" $HOME`, "events.fifo")
+	if base := filepath.Base(quoted); strings.ContainsAny(base, `'"#{}$ `) {
+		t.Errorf("Path = %s, want shell and format characters replaced", base)
+	}
+	if alike, _ := Path("/tmp/tmux-1000/default", `it_s__#{main}__$HOME`, "events.fifo"); alike == quoted {
+		t.Errorf("session names that only differ in replaced characters share %s", alike)
+	}
+}
@@ -0,0 +1,134 @@
+// Package socket serves newline-delimited JSON requests on a Unix socket
+package socket
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+)
+
+// maxRequestSize bounds a single request line; summaries can be long
+const maxRequestSize = 1 << 20
+
+// HandlerFunc answers one request line; the value it returns is encoded as
+// the response line
+type HandlerFunc func(line []byte) interface{}
+
+// Server accepts requests on a Unix socket
+// The protocol is newline-delimited JSON: each line is a request and is
+// answered by exactly one response line
+type Server struct {
+	path     string
+	listener net.Listener
+	handle   HandlerFunc
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+}
+
+// Listen creates the socket at path and returns a server ready to Serve
+// A socket left behind by an instance that did not exit cleanly is replaced,
+// but one that still accepts connections is reported as an error
+func Listen(path string, handle HandlerFunc) (*Server, error) {
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("socket %s is already in use", path)
+		}
+		os.Remove(path)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", path, err)
+	}
+
+	// Requests drive the user's terminal, so only the user may connect
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("restrict socket permissions: %w", err)
+	}
+
+	return &Server{
+		path:     path,
+		listener: listener,
+		handle:   handle,
+		conns:    make(map[net.Conn]struct{}),
+	}, nil
+}
+
+// Path returns the socket path
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Serve accepts connections until Close is called
+func (s *Server) Serve() error {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("accept: %w", err)
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn answers requests on one connection until the client hangs up
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), maxRequestSize)
+	encoder := json.NewEncoder(conn)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if err := encoder.Encode(s.handle(line)); err != nil {
+			return
+		}
+	}
+}
+
+// Close stops accepting connections, hangs up on clients and removes the socket
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	os.Remove(s.path)
+	return err
+}
@@ -0,0 +1,77 @@
+package socket
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// listen serves an echo handler on a socket in a temporary directory
+func listen(t *testing.T) *Server {
+	t.Helper()
+	srv, err := Listen(filepath.Join(t.TempDir(), "test.sock"), func(line []byte) interface{} {
+		return map[string]string{"echo": string(line)}
+	})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	go srv.Serve()
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+func TestServeAnswersEachLine(t *testing.T) {
+	srv := listen(t)
+	conn, err := net.Dial("unix", srv.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("one\n\ntwo\n")); err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(conn)
+	for _, want := range []string{"one", "two"} {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("read response: %v", err)
+		}
+		var resp map[string]string
+		if err := json.Unmarshal(line, &resp); err != nil {
+			t.Fatalf("parse %q: %v", line, err)
+		}
+		if resp["echo"] != want {
+			t.Errorf("response = %v, want echo %q", resp, want)
+		}
+	}
+}
+
+func TestListenReplacesStaleSocket(t *testing.T) {
+	srv := listen(t)
+	if info, err := os.Stat(srv.Path()); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("socket mode = %v, %v, want 0600", info, err)
+	}
+
+	// A live socket is not taken over
+	if _, err := Listen(srv.Path(), nil); err == nil {
+		t.Fatalf("Listen took over a socket in use")
+	}
+
+	// Once its owner is gone, a leftover file is replaced
+	path := filepath.Join(t.TempDir(), "stale.sock")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := Listen(path, nil)
+	if err != nil {
+		t.Fatalf("Listen over a stale socket: %v", err)
+	}
+	stale.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Close left the socket behind")
+	}
+}
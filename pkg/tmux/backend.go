@@ -0,0 +1,249 @@
+package tmux
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AIBackend describes a CLI that AI chats run
+type AIBackend struct {
+	Name        string            // Identifies the backend in config, pane options and the TUI
+	Command     []string          // Program and arguments
+	Env         map[string]string // Extra environment variables
+	Prefix      string            // Status bar tab prefix ("" = Name)
+	SessionArgs []string          // Arguments giving a new chat its session ID; "{session}" is replaced
+	ResumeArgs  []string          // Arguments resuming a session; "{session}" is replaced
+	PromptArgs  []string          // Arguments passing a prompt; "{prompt}" is replaced (nil = the prompt alone)
+}
+
+// defaultAIBackend is used until SetAIBackends is called
+var defaultAIBackend = AIBackend{
+	Name:    "claude",
+	Command: []string{"claude"},
+	Prefix:  "ai",
+}
+
+// sessionPlaceholder is replaced by the chat's session ID in SessionArgs and
+// ResumeArgs
+const sessionPlaceholder = "{session}"
+
+// promptPlaceholder is replaced by the chat's opening prompt in PromptArgs
+const promptPlaceholder = "{prompt}"
+
+// AIChatOptions controls how StartAIChat starts a chat
+type AIChatOptions struct {
+	Backend string // Backend name ("" = the selected backend)
+	Prompt  string // First message of the chat ("" = none)
+	Resume  string // Session ID to resume instead of starting a new session
+}
+
+// AIChat describes an open AI chat
+type AIChat struct {
+	ID      string
+	PaneID  string
+	Backend string // Backend name
+	Session string // Session ID ("" if the backend has no session arguments)
+	Title   string // User-chosen title ("" = untitled)
+}
+
+// SetAIBackends replaces the configured AI backends and selects the first
+func (m *Manager) SetAIBackends(backends []AIBackend) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(backends) == 0 {
+		backends = []AIBackend{defaultAIBackend}
+	}
+	m.aiBackends = append([]AIBackend(nil), backends...)
+	m.aiBackend = m.aiBackends[0].Name
+}
+
+// SelectAIBackend chooses the backend new AI chats use
+func (m *Manager) SelectAIBackend(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.findAIBackend(name); !ok {
+		return fmt.Errorf("unknown AI backend %q", name)
+	}
+	m.aiBackend = name
+	return nil
+}
+
+// GetAIBackend returns the name of the backend new AI chats use
+func (m *Manager) GetAIBackend() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.aiBackend
+}
+
+// GetAIBackends returns the names of the configured backends in order
+func (m *Manager) GetAIBackends() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, len(m.aiBackends))
+	for i, backend := range m.aiBackends {
+		names[i] = backend.Name
+	}
+	return names
+}
+
+// GetAIChats returns the open AI chats in status bar tab order, so the Nth
+// chat is the one whose tab is labelled N
+func (m *Manager) GetAIChats() []AIChat {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := m.sortedAIChatIDs()
+	chats := make([]AIChat, 0, len(ids))
+	for _, aiID := range ids {
+		chats = append(chats, AIChat{
+			ID:      aiID,
+			PaneID:  m.aiPanes[aiID],
+			Backend: m.aiChatBackends[aiID],
+			Session: m.aiSessions[aiID],
+			Title:   m.aiTitles[aiID],
+		})
+	}
+	return chats
+}
+
+// sortedAIChatIDs returns the open AI chats grouped by backend in
+// configuration order, then by number
+func (m *Manager) sortedAIChatIDs() []string {
+	ids := make([]string, 0, len(m.aiPanes))
+	for aiID := range m.aiPanes {
+		ids = append(ids, aiID)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		bi, bj := m.aiBackendIndex(ids[i]), m.aiBackendIndex(ids[j])
+		if bi != bj {
+			return bi < bj
+		}
+		return aiChatLess(ids[i], ids[j])
+	})
+	return ids
+}
+
+// aiChatLess orders AI chat IDs by number so ai-10 follows ai-9
+func aiChatLess(a, b string) bool {
+	var na, nb int
+	_, errA := fmt.Sscanf(a, "ai-%d", &na)
+	_, errB := fmt.Sscanf(b, "ai-%d", &nb)
+	if errA != nil || errB != nil || na == nb {
+		return a < b
+	}
+	return na < nb
+}
+
+// findAIBackend looks up a configured backend by name
+func (m *Manager) findAIBackend(name string) (AIBackend, bool) {
+	for _, backend := range m.aiBackends {
+		if backend.Name == name {
+			return backend, true
+		}
+	}
+	return AIBackend{}, false
+}
+
+// aiPrefix returns the status bar prefix for an AI chat's backend
+// Chats re-adopted from a backend that is no longer configured use its name
+func (m *Manager) aiPrefix(aiChatID string) string {
+	name := m.aiChatBackends[aiChatID]
+	if backend, ok := m.findAIBackend(name); ok {
+		if backend.Prefix != "" {
+			return backend.Prefix
+		}
+		return backend.Name
+	}
+	if name != "" {
+		return name
+	}
+	return defaultAIBackend.Prefix
+}
+
+// aiBackendIndex orders AI chats by the position of their backend in the
+// configuration; unknown backends sort last
+func (m *Manager) aiBackendIndex(aiChatID string) int {
+	name := m.aiChatBackends[aiChatID]
+	for i, backend := range m.aiBackends {
+		if backend.Name == name {
+			return i
+		}
+	}
+	return len(m.aiBackends)
+}
+
+// aiChatCommand returns the new-window arguments that start a chat with
+// backend, and the chat's session ID
+func aiChatCommand(backend AIBackend, opts AIChatOptions) ([]string, string, error) {
+	if len(backend.Command) == 0 {
+		return nil, "", fmt.Errorf("AI backend %q has no command", backend.Name)
+	}
+
+	var args []string
+	envKeys := make([]string, 0, len(backend.Env))
+	for key := range backend.Env {
+		envKeys = append(envKeys, key)
+	}
+	sort.Strings(envKeys)
+	for _, key := range envKeys {
+		args = append(args, "-e", key+"="+backend.Env[key])
+	}
+	args = append(args, backend.Command...)
+
+	session := ""
+	switch {
+	case opts.Resume != "":
+		if len(backend.ResumeArgs) == 0 {
+			return nil, "", fmt.Errorf("AI backend %q cannot resume sessions", backend.Name)
+		}
+		session = opts.Resume
+		args = append(args, expandSession(backend.ResumeArgs, session)...)
+	case len(backend.SessionArgs) > 0:
+		id, err := newSessionID()
+		if err != nil {
+			return nil, "", err
+		}
+		session = id
+		args = append(args, expandSession(backend.SessionArgs, session)...)
+	}
+
+	// The prompt is a separate argument so tmux passes it through unparsed
+	// Backends that take it as a flag, e.g. "-p {prompt}", say so in
+	// PromptArgs; without a prompt those arguments are left out entirely
+	if opts.Prompt != "" {
+		promptArgs := backend.PromptArgs
+		if promptArgs == nil {
+			promptArgs = []string{promptPlaceholder}
+		}
+		for _, arg := range promptArgs {
+			args = append(args, strings.ReplaceAll(arg, promptPlaceholder, opts.Prompt))
+		}
+	}
+	return args, session, nil
+}
+
+// expandSession substitutes the session ID into backend arguments
+func expandSession(args []string, session string) []string {
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		expanded[i] = strings.ReplaceAll(arg, sessionPlaceholder, session)
+	}
+	return expanded
+}
+
+// newSessionID returns a random version 4 UUID
+func newSessionID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generate session ID: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
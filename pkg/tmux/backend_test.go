@@ -0,0 +1,68 @@
+package tmux
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAIChatCommandPromptPlacement(t *testing.T) {
+	const prompt = `Investigating "a1b2"; it's $HOME`
+	tests := []struct {
+		name    string
+		backend AIBackend
+		opts    AIChatOptions
+		want    []string
+	}{
+		{
+			name:    "last by default",
+			backend: AIBackend{Command: []string{"--model", "x"}},
+			opts:    AIChatOptions{Prompt: prompt},
+			want:    []string{"--model", "x", prompt},
+		},
+		{
+			name:    "flag",
+			backend: AIBackend{Command: []string{"--model", "x"}, PromptArgs: []string{"-i", "{prompt}"}},
+			opts:    AIChatOptions{Prompt: prompt},
+			want:    []string{"--model", "x", "-i", prompt},
+		},
+		{
+			name:    "inside an argument",
+			backend: AIBackend{PromptArgs: []string{"--message={prompt}"}},
+			opts:    AIChatOptions{Prompt: prompt},
+			want:    []string{"--message=" + prompt},
+		},
+		{
+			name:    "after session arguments",
+			backend: AIBackend{ResumeArgs: []string{"--resume", "{session}"}, PromptArgs: []string{"-p", "{prompt}"}},
+			opts:    AIChatOptions{Prompt: prompt, Resume: "s1"},
+			want:    []string{"--resume", "s1", "-p", prompt},
+		},
+		{
+			name:    "no prompt",
+			backend: AIBackend{Command: []string{"--model", "x"}, PromptArgs: []string{"-i", "{prompt}"}},
+			want:    []string{"--model", "x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := tt.backend
+			backend.Command = append([]string{"testdata/print-args.sh"}, backend.Command...)
+			args, _, err := aiChatCommand(backend, tt.opts)
+			if err != nil {
+				t.Fatalf("aiChatCommand: %v", err)
+			}
+
+			// Run the command as tmux would, without a shell in between
+			output, err := exec.Command(args[0], args[1:]...).Output()
+			if err != nil {
+				t.Fatalf("run %q: %v", args, err)
+			}
+			got := strings.Split(strings.TrimSuffix(string(output), "\n"), "\n")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CLI got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
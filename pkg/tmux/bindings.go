@@ -0,0 +1,153 @@
+package tmux
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Global user options remembering the root table bindings muxctl replaced,
+// so Cleanup can put them back and a muxctl restarted after a crash restores
+// the user's binding rather than its predecessor's
+const (
+	optKeySavedPrefix = "@muxctl-saved-key-" // Followed by the key; the user's list-keys line, or keyUnbound
+	optKeyOwnPrefix   = "@muxctl-own-key-"   // Followed by the key; muxctl's own list-keys line
+)
+
+// keyUnbound is saved for keys that had no binding
+const keyUnbound = "none"
+
+// bindKey binds a root table key to a muxctl command, saving the user's
+// binding first
+func (m *Manager) bindKey(key string, command ...string) error {
+	saved, err := m.showOption(optKeySavedPrefix + key)
+	if err != nil {
+		return err
+	}
+	if saved == "" {
+		line, err := m.listKey(key)
+		if err != nil {
+			return fmt.Errorf("save %s binding: %w", key, err)
+		}
+		if line == "" {
+			line = keyUnbound
+		}
+		m.tmuxCmd("set-option", "-g", optKeySavedPrefix+key, line)
+	}
+
+	if err := m.tmuxCmd2(append([]string{"bind-key", "-n", key}, command...)...); err != nil {
+		return fmt.Errorf("bind %s: %w", key, err)
+	}
+
+	// Remember what muxctl bound so Cleanup can tell whether the user has
+	// rebound the key since
+	own, err := m.listKey(key)
+	if err != nil {
+		return fmt.Errorf("read %s binding: %w", key, err)
+	}
+	m.tmuxCmd("set-option", "-g", optKeyOwnPrefix+key, own)
+	return nil
+}
+
+// restoreKey puts back the binding bindKey saved for a root table key,
+// unless the key was rebound after muxctl bound it
+func (m *Manager) restoreKey(key string) {
+	saved, err := m.showOption(optKeySavedPrefix + key)
+	if err != nil || saved == "" {
+		return
+	}
+	own, _ := m.showOption(optKeyOwnPrefix + key)
+	current, err := m.listKey(key)
+
+	if err == nil && sameBinding(current, own) {
+		if saved == keyUnbound {
+			m.tmuxCmd("unbind-key", "-n", key)
+		} else if err := m.sourceLine(saved); err != nil {
+			// Better tmux's default than a binding into a muxctl that is gone
+			m.tmuxCmd("unbind-key", "-n", key)
+		}
+	}
+	m.tmuxCmd("set-option", "-gu", optKeySavedPrefix+key)
+	m.tmuxCmd("set-option", "-gu", optKeyOwnPrefix+key)
+}
+
+// userBinding returns the command the user had bound to a root table key
+// before muxctl replaced it, or "" if the key was unbound
+func (m *Manager) userBinding(key string) (string, error) {
+	line, err := m.showOption(optKeySavedPrefix + key)
+	if err != nil {
+		return "", err
+	}
+	if line == "" {
+		if line, err = m.listKey(key); err != nil {
+			return "", fmt.Errorf("read %s binding: %w", key, err)
+		}
+	}
+	if line == "" || line == keyUnbound {
+		return "", nil
+	}
+	return bindingCommand(line), nil
+}
+
+// bindingCommand returns the command of a list-keys line such as
+// "bind-key -r -T root MouseDown1Status select-window -t =", still quoted
+// as tmux printed it
+func bindingCommand(line string) string {
+	rest := line
+	next := func() string {
+		rest = strings.TrimLeft(rest, " \t")
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			end = len(rest)
+		}
+		word := rest[:end]
+		rest = rest[end:]
+		return word
+	}
+
+	next() // bind-key
+	for word := next(); strings.HasPrefix(word, "-"); word = next() {
+		if word == "-T" {
+			next()
+		}
+	}
+	// The loop consumed the key
+	return strings.TrimSpace(rest)
+}
+
+// listKey returns the list-keys line binding a root table key, or "" if the
+// key is unbound
+func (m *Manager) listKey(key string) (string, error) {
+	output, err := m.tmuxCmd("list-keys", "-T", "root", key)
+	if err != nil {
+		if strings.Contains(output+err.Error(), "unknown key") {
+			return "", nil
+		}
+		return "", err
+	}
+	return output, nil
+}
+
+// sameBinding compares list-keys lines, which tmux pads to align the keys
+// of a whole table
+func sameBinding(a, b string) bool {
+	return strings.Join(strings.Fields(a), " ") == strings.Join(strings.Fields(b), " ")
+}
+
+// sourceLine runs a line of tmux configuration, such as a saved bind-key
+// command, letting tmux parse its quoting
+func (m *Manager) sourceLine(line string) error {
+	f, err := os.CreateTemp("", "muxctl-*.conf")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return m.tmuxCmd2("source-file", f.Name())
+}
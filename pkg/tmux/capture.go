@@ -0,0 +1,150 @@
+package tmux
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CaptureOptions selects what CapturePane captures
+// Line numbers are relative to the top of the visible screen, so 0 is its
+// first line and negative lines are scrollback history
+type CaptureOptions struct {
+	Start     *int // First line (nil = the top of the visible screen); see Line
+	End       *int // Last line (nil = the bottom of the visible screen)
+	History   bool // Start at the top of the scrollback history (-S -) instead of Start
+	Lines     int  // Capture the last Lines lines up to the cursor instead, including scrollback
+	Join      bool // Join wrapped lines and keep trailing spaces (-J)
+	Escapes   bool // Keep colours and attributes as ANSI escape sequences (-e)
+	Alternate bool // Capture the alternate screen, e.g. what is behind a full-screen program (-a)
+}
+
+// Line returns a line number for CaptureOptions.Start and End
+func Line(n int) *int {
+	return &n
+}
+
+// WithCaptureFilter makes CapturePane and CaptureSelection pass captured
+// text through filter before returning it, e.g. to redact secrets
+func WithCaptureFilter(filter func(string) string) Option {
+	return func(m *Manager) {
+		m.captureFilter = filter
+	}
+}
+
+// filterCapture applies the capture filter, if any
+func (m *Manager) filterCapture(text string) string {
+	if m.captureFilter == nil {
+		return text
+	}
+	return m.captureFilter(text)
+}
+
+// Capture is the content of a pane and its state when it was captured
+type Capture struct {
+	Content     string
+	Width       int  // Pane width in columns
+	Height      int  // Pane height in rows
+	CursorX     int  // Cursor column
+	CursorY     int  // Cursor row, relative to the top of the visible screen
+	HistorySize int  // Lines of scrollback above the visible screen
+	AltScreen   bool // A full-screen program has switched to the alternate screen
+}
+
+// CapturePane captures the content of a pane
+// The content has been through the capture filter
+func (m *Manager) CapturePane(paneID string, opts CaptureOptions) (*Capture, error) {
+	format := "#{pane_width}\t#{pane_height}\t#{cursor_x}\t#{cursor_y}\t#{history_size}\t#{alternate_on}"
+	info, err := m.tmuxCmd("display-message", "-p", "-t", paneID, format)
+	if err != nil {
+		return nil, fmt.Errorf("get pane size: %w", err)
+	}
+	fields := strings.Split(info, "\t")
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("get pane size: unexpected output %q", info)
+	}
+	number := func(i int) int {
+		n, _ := strconv.Atoi(fields[i])
+		return n
+	}
+	capture := &Capture{
+		Width:       number(0),
+		Height:      number(1),
+		CursorX:     number(2),
+		CursorY:     number(3),
+		HistorySize: number(4),
+		AltScreen:   fields[5] == "1",
+	}
+
+	args := []string{"capture-pane", "-p", "-t", paneID}
+	if opts.Lines > 0 {
+		start := capture.CursorY - opts.Lines + 1
+		args = append(args, "-S", strconv.Itoa(start), "-E", strconv.Itoa(capture.CursorY))
+	} else {
+		if opts.History {
+			args = append(args, "-S", "-")
+		} else if opts.Start != nil {
+			args = append(args, "-S", strconv.Itoa(*opts.Start))
+		}
+		if opts.End != nil {
+			args = append(args, "-E", strconv.Itoa(*opts.End))
+		}
+	}
+	if opts.Join {
+		args = append(args, "-J")
+	}
+	if opts.Escapes {
+		args = append(args, "-e")
+	}
+	if opts.Alternate {
+		args = append(args, "-a")
+	}
+
+	capture.Content, err = m.tmuxCmd(args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture pane: %w", err)
+	}
+	capture.Content = m.filterCapture(capture.Content)
+	return capture, nil
+}
+
+// CaptureSelection returns the text selected in a pane's copy mode
+// ok is false when the pane is not in copy mode or has no selection
+func (m *Manager) CaptureSelection(paneID string) (text string, ok bool, err error) {
+	state, err := m.tmuxCmd("display-message", "-p", "-t", paneID, "#{pane_in_mode}#{selection_present}")
+	if err != nil {
+		return "", false, fmt.Errorf("get selection: %w", err)
+	}
+	if state != "11" {
+		return "", false, nil
+	}
+
+	// Copying pushes a new automatic buffer on top of the stack; read it
+	// and drop it again so the user's buffers are left as they were
+	// An empty selection pushes nothing, so the top buffer is compared to
+	// avoid returning an older one
+	before := m.topBuffer()
+	if err := m.tmuxCmd2("send-keys", "-t", paneID, "-X", "copy-selection-no-clear"); err != nil {
+		return "", false, fmt.Errorf("copy selection: %w", err)
+	}
+	buffer := m.topBuffer()
+	if buffer == "" || buffer == before {
+		return "", false, nil
+	}
+	text, err = m.tmuxCmd("show-buffer", "-b", buffer)
+	if err != nil {
+		return "", false, fmt.Errorf("read selection: %w", err)
+	}
+	m.tmuxCmd("delete-buffer", "-b", buffer)
+	return m.filterCapture(text), true, nil
+}
+
+// topBuffer returns the name of the most recent paste buffer, or ""
+func (m *Manager) topBuffer() string {
+	output, err := m.tmuxCmd("list-buffers", "-F", "#{buffer_name}")
+	if err != nil {
+		return ""
+	}
+	name, _, _ := strings.Cut(output, "\n")
+	return name
+}
@@ -0,0 +1,341 @@
+package tmux
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Control-mode notification names (without the leading %)
+const (
+	NotifyWindowAdd           = "window-add"
+	NotifyWindowClose         = "window-close"
+	NotifyUnlinkedWindowClose = "unlinked-window-close"
+	NotifyLayoutChange        = "layout-change"
+	NotifyPaneModeChanged     = "pane-mode-changed"
+	NotifyOutput              = "output"
+	NotifySessionChanged      = "session-changed"
+	NotifyExit                = "exit"
+)
+
+// Notification is an asynchronous event reported by tmux in control mode,
+// such as "%window-close @3"
+type Notification struct {
+	Name string   // Notification name without the leading %, e.g. "window-close"
+	Args []string // Arguments; for %output the second argument is the escaped payload
+}
+
+// Notifier is implemented by runners that can report asynchronous tmux events
+type Notifier interface {
+	// Subscribe returns a channel of notifications and a function that
+	// cancels the subscription
+	Subscribe() (<-chan Notification, func())
+}
+
+// controlReply is the result of one command sent over the control connection
+type controlReply struct {
+	output string
+	err    error
+}
+
+// ControlRunner multiplexes tmux commands over a single persistent
+// `tmux -C` connection instead of spawning one process per command
+// Replies are matched to commands in order via the %begin/%end/%error blocks,
+// and everything outside a reply block is delivered to subscribers
+type ControlRunner struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	mu      sync.Mutex          // Guards writes to stdin and the pending queue
+	pending []chan controlReply // Reply channels in command order
+	closed  bool
+
+	subsMu  sync.Mutex
+	subs    map[int]chan Notification
+	nextSub int
+
+	done chan struct{} // Closed when the connection ends
+}
+
+// NewControlRunner starts a tmux control-mode client attached to target
+// (a session, window or pane; empty means the most recent session)
+// Any extra arguments are passed to tmux before -C, e.g. "-L", "name"
+func NewControlRunner(target string, tmuxArgs ...string) (*ControlRunner, error) {
+	args := append(append([]string(nil), tmuxArgs...), "-C", "attach-session")
+	if target != "" {
+		args = append(args, "-t", target)
+	}
+
+	cmd := exec.Command("tmux", args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("control stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("control stdout: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start control client: %w", err)
+	}
+
+	r := &ControlRunner{
+		cmd:   cmd,
+		stdin: stdin,
+		subs:  make(map[int]chan Notification),
+		done:  make(chan struct{}),
+	}
+	go r.readLoop(stdout)
+	return r, nil
+}
+
+// Run sends a command over the control connection and waits for its reply
+func (r *ControlRunner) Run(args ...string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("no command")
+	}
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteControlArg(arg)
+	}
+
+	ch := make(chan controlReply, 1)
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return "", fmt.Errorf("control connection closed")
+	}
+	if _, err := io.WriteString(r.stdin, strings.Join(quoted, " ")+"\n"); err != nil {
+		r.mu.Unlock()
+		return "", fmt.Errorf("write control command: %w", err)
+	}
+	r.pending = append(r.pending, ch)
+	r.mu.Unlock()
+
+	select {
+	case reply := <-ch:
+		return reply.output, reply.err
+	case <-r.done:
+		// The reply may have been delivered just before the connection ended
+		select {
+		case reply := <-ch:
+			return reply.output, reply.err
+		default:
+			return "", fmt.Errorf("control connection closed")
+		}
+	}
+}
+
+// Subscribe returns a channel of control-mode notifications
+// Notifications are dropped for subscribers that fall behind; the channel is
+// closed when the connection ends or the returned cancel function is called
+func (r *ControlRunner) Subscribe() (<-chan Notification, func()) {
+	ch := make(chan Notification, 64)
+
+	r.subsMu.Lock()
+	select {
+	case <-r.done:
+		r.subsMu.Unlock()
+		close(ch)
+		return ch, func() {}
+	default:
+	}
+	id := r.nextSub
+	r.nextSub++
+	r.subs[id] = ch
+	r.subsMu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			r.subsMu.Lock()
+			defer r.subsMu.Unlock()
+			if _, ok := r.subs[id]; ok {
+				delete(r.subs, id)
+				close(ch)
+			}
+		})
+	}
+}
+
+// Done returns a channel that is closed when the control connection ends
+func (r *ControlRunner) Done() <-chan struct{} {
+	return r.done
+}
+
+// Close detaches the control client and waits for it to exit
+func (r *ControlRunner) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.stdin.Close()
+	r.mu.Unlock()
+
+	<-r.done
+	return r.cmd.Wait()
+}
+
+// readLoop parses control-mode output until the connection ends
+func (r *ControlRunner) readLoop(stdout io.Reader) {
+	defer r.shutdown()
+
+	reader := bufio.NewReader(stdout)
+	var (
+		inBlock bool
+		block   string   // "<time> <command number>" of the current block
+		ours    bool     // Whether the current block answers one of our commands
+		body    []string // Lines collected inside the current block
+	)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSuffix(line, "\n")
+
+		if inBlock {
+			// Output such as a captured pane may contain lines that look like
+			// %end; only the one repeating the %begin values ends the block
+			isEnd := strings.HasPrefix(line, "%end ") && blockID(line) == block
+			isError := strings.HasPrefix(line, "%error ") && blockID(line) == block
+			if !isEnd && !isError {
+				body = append(body, line)
+				continue
+			}
+			inBlock = false
+			if ours {
+				output := strings.Join(body, "\n")
+				reply := controlReply{output: output}
+				if isError {
+					reply = controlReply{err: fmt.Errorf("%s", output)}
+				}
+				r.deliver(reply)
+			}
+			body = nil
+			continue
+		}
+
+		if strings.HasPrefix(line, "%begin ") {
+			// %begin <time> <command number> <flags>; flags is 1 for commands
+			// sent by this client and 0 for the initial attach
+			fields := strings.Fields(line)
+			inBlock = true
+			block = blockID(line)
+			ours = len(fields) >= 4 && fields[3] == "1"
+			continue
+		}
+
+		if strings.HasPrefix(line, "%") {
+			r.notify(parseNotification(line))
+			if strings.HasPrefix(line, "%exit") {
+				return
+			}
+		}
+	}
+}
+
+// blockID returns the time and command number of a %begin, %end or %error
+// line, which tmux repeats to pair them
+func blockID(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return ""
+	}
+	return fields[1] + " " + fields[2]
+}
+
+// deliver hands a reply to the oldest pending command
+func (r *ControlRunner) deliver(reply controlReply) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.pending) == 0 {
+		return
+	}
+	ch := r.pending[0]
+	r.pending = r.pending[1:]
+	ch <- reply
+}
+
+// notify fans a notification out to all subscribers without blocking
+func (r *ControlRunner) notify(n Notification) {
+	r.subsMu.Lock()
+	defer r.subsMu.Unlock()
+
+	for _, ch := range r.subs {
+		select {
+		case ch <- n:
+		default:
+		}
+	}
+}
+
+// shutdown fails pending commands and closes subscriber channels
+func (r *ControlRunner) shutdown() {
+	r.mu.Lock()
+	r.closed = true
+	for _, ch := range r.pending {
+		ch <- controlReply{err: fmt.Errorf("control connection closed")}
+	}
+	r.pending = nil
+	r.mu.Unlock()
+
+	r.subsMu.Lock()
+	close(r.done)
+	for id, ch := range r.subs {
+		delete(r.subs, id)
+		close(ch)
+	}
+	r.subsMu.Unlock()
+}
+
+// parseNotification splits a notification line such as "%window-close @3"
+// The %output payload may contain spaces, so it is kept as a single argument
+func parseNotification(line string) Notification {
+	line = strings.TrimPrefix(line, "%")
+	name, rest, _ := strings.Cut(line, " ")
+	if name == NotifyOutput {
+		paneID, payload, _ := strings.Cut(rest, " ")
+		return Notification{Name: name, Args: []string{paneID, payload}}
+	}
+	return Notification{Name: name, Args: strings.Fields(rest)}
+}
+
+// quoteControlArg quotes an argument for the tmux command parser
+// Arguments containing anything beyond a conservative safe set are double
+// quoted with backslash escapes so newlines cannot split the command line
+func quoteControlArg(arg string) string {
+	if arg != "" && strings.IndexFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			strings.ContainsRune("-_./:,=%@+", r))
+	}) < 0 {
+		return arg
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range arg {
+		switch r {
+		case '"', '\\', '$':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
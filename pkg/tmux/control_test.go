@@ -0,0 +1,88 @@
+package tmux
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// scriptedRunner returns a ControlRunner with no tmux process behind it and
+// the reply channels of n commands, for feeding readLoop scripted output
+func scriptedRunner(n int) (*ControlRunner, []chan controlReply) {
+	r := &ControlRunner{
+		subs: make(map[int]chan Notification),
+		done: make(chan struct{}),
+	}
+	replies := make([]chan controlReply, n)
+	for i := range replies {
+		replies[i] = make(chan controlReply, 1)
+		r.pending = append(r.pending, replies[i])
+	}
+	return r, replies
+}
+
+func TestReadLoop(t *testing.T) {
+	r, replies := scriptedRunner(2)
+	notifications, _ := r.Subscribe()
+
+	r.readLoop(strings.NewReader(strings.Join([]string{
+		// The reply to attaching is not ours
+		"%begin 1700000000 5 0",
+		"%end 1700000000 5 0",
+		`%output %1 hello\040world \134`,
+		// A captured pane showing control-mode output of its own
+		"%begin 1700000001 6 1",
+		"line one",
+		"%end 1700000000 5 1",
+		"%error 1 2 1",
+		"",
+		"%end 1700000001 6 1",
+		"%window-close @3",
+		"%begin 1700000002 7 1",
+		"unknown command: foo",
+		"%error 1700000002 7 1",
+		"%layout-change @1 b25d,80x24,0,0,1 b25d,80x24,0,0,1 *",
+	}, "\n") + "\n"))
+
+	first := <-replies[0]
+	if want := "line one\n%end 1700000000 5 1\n%error 1 2 1\n"; first.err != nil || first.output != want {
+		t.Errorf("first reply = %q, %v, want %q", first.output, first.err, want)
+	}
+	second := <-replies[1]
+	if second.err == nil || second.err.Error() != "unknown command: foo" {
+		t.Errorf("second reply = %q, %v, want the error", second.output, second.err)
+	}
+
+	var got []Notification
+	for n := range notifications {
+		got = append(got, n)
+	}
+	want := []Notification{
+		{Name: NotifyOutput, Args: []string{"%1", `hello\040world \134`}},
+		{Name: NotifyWindowClose, Args: []string{"@3"}},
+		{Name: NotifyLayoutChange, Args: []string{"@1", "b25d,80x24,0,0,1", "b25d,80x24,0,0,1", "*"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications = %q, want %q", got, want)
+	}
+}
+
+func TestParseNotification(t *testing.T) {
+	tests := []struct {
+		line string
+		want Notification
+	}{
+		{"%window-add @2", Notification{Name: NotifyWindowAdd, Args: []string{"@2"}}},
+		{"%output %3 a  b\\015\\012", Notification{Name: NotifyOutput, Args: []string{"%3", "a  b\\015\\012"}}},
+		{"%output %3 ", Notification{Name: NotifyOutput, Args: []string{"%3", ""}}},
+		{"%session-changed $1 main", Notification{Name: NotifySessionChanged, Args: []string{"$1", "main"}}},
+		{"%exit", Notification{Name: NotifyExit, Args: []string{}}},
+	}
+	for _, tt := range tests {
+		got := parseNotification(tt.line)
+		if got.Name != tt.want.Name || len(got.Args) != len(tt.want.Args) ||
+			(len(got.Args) > 0 && !reflect.DeepEqual(got.Args, tt.want.Args)) {
+			t.Errorf("parseNotification(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
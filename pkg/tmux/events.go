@@ -0,0 +1,182 @@
+package tmux
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/xunzhou/muxctl/pkg/rundir"
+)
+
+// EventType identifies a pane lifecycle change reported by a tmux hook
+type EventType string
+
+const (
+	EventPaneDied       EventType = "pane-died"       // Pane process exited with remain-on-exit set
+	EventPaneExited     EventType = "pane-exited"     // Pane process exited and the pane was closed
+	EventWindowUnlinked EventType = "window-unlinked" // Window was closed or unlinked from the session
+	EventPaneKilled     EventType = "after-kill-pane" // Pane was closed with kill-pane
+)
+
+// lifecycleHooks are the tmux hooks installed by StartEvents
+var lifecycleHooks = []EventType{
+	EventPaneDied,
+	EventPaneExited,
+	EventWindowUnlinked,
+	EventPaneKilled,
+}
+
+// Event is a pane lifecycle change
+// PaneID and WindowID are empty when tmux no longer knows the object, e.g.
+// after-kill-pane runs once the pane is already gone
+type Event struct {
+	Type     EventType
+	PaneID   string
+	WindowID string
+}
+
+// EventsPath returns the FIFO path used for hook notifications in a session
+// of the tmux server listening on serverSocket
+func EventsPath(serverSocket, sessionName string) (string, error) {
+	return rundir.Path(serverSocket, sessionName, "events.fifo")
+}
+
+// StartEvents installs tmux hooks that report pane lifecycle changes through
+// a FIFO and starts delivering them on the Events channel
+func (m *Manager) StartEvents() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.events != nil {
+		return nil
+	}
+
+	sessionName, err := m.SessionName()
+	if err != nil {
+		return fmt.Errorf("get session name: %w", err)
+	}
+
+	serverSocket, err := m.ServerSocket()
+	if err != nil {
+		return fmt.Errorf("get server socket: %w", err)
+	}
+	path, err := EventsPath(serverSocket, sessionName)
+	if err != nil {
+		return err
+	}
+
+	// Drop hooks left behind by a previous instance that did not exit cleanly
+	m.removeHooks(path)
+	os.Remove(path)
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		return fmt.Errorf("create events fifo: %w", err)
+	}
+
+	// Open read-write so the reader never sees EOF between hook writes
+	fifo, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("open events fifo: %w", err)
+	}
+
+	// Append hooks rather than replacing them so user-defined hooks keep
+	// working; StopEvents finds ours again by the FIFO name
+	// The path is quoted for the shell, then "#" is doubled so run-shell
+	// does not expand it as a format, then the whole command is quoted for
+	// the tmux parser
+	for _, hook := range lifecycleHooks {
+		shell := fmt.Sprintf("echo %s #{hook_pane} #{hook_window} >> %s", hook,
+			strings.ReplaceAll(shellQuote(path), "#", "##"))
+		cmd := "run-shell -b " + quoteControlArg(shell)
+		if err := m.tmuxCmd2("set-hook", "-ga", string(hook), cmd); err != nil {
+			fifo.Close()
+			os.Remove(path)
+			m.removeHooks(path)
+			return fmt.Errorf("install %s hook: %w", hook, err)
+		}
+	}
+
+	m.eventsPath = path
+	m.eventsFIFO = fifo
+	m.events = make(chan Event, 16)
+	go readEvents(fifo, m.events)
+	return nil
+}
+
+// Events returns the channel of pane lifecycle events, or nil if StartEvents
+// has not been called
+func (m *Manager) Events() <-chan Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.events
+}
+
+// StopEvents removes the hooks installed by StartEvents and closes the FIFO
+func (m *Manager) StopEvents() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stopEvents()
+}
+
+// stopEvents implements StopEvents; the caller holds m.mu
+func (m *Manager) stopEvents() {
+	if m.eventsFIFO == nil {
+		return
+	}
+	m.removeHooks(m.eventsPath)
+	m.eventsFIFO.Close()
+	os.Remove(m.eventsPath)
+	m.eventsFIFO = nil
+	m.eventsPath = ""
+	// readEvents closes the old channel; a later StartEvents makes a new one
+	m.events = nil
+}
+
+// removeHooks unsets every hook entry that writes to the given FIFO
+// Entries are matched by the FIFO's file name, which rundir keeps free of
+// characters tmux would show quoted
+func (m *Manager) removeHooks(path string) {
+	name := filepath.Base(path)
+	for _, hook := range lifecycleHooks {
+		output, err := m.tmuxCmd("show-hooks", "-g", string(hook))
+		if err != nil {
+			continue
+		}
+		// Each line looks like: pane-exited[3] run-shell -b "..."
+		for _, line := range strings.Split(output, "\n") {
+			entry, _, found := strings.Cut(line, " ")
+			if found && strings.Contains(line, name) {
+				m.tmuxCmd("set-hook", "-gu", entry)
+			}
+		}
+	}
+}
+
+// readEvents parses hook lines ("type pane window") until the FIFO is closed
+func readEvents(fifo *os.File, events chan<- Event) {
+	defer close(events)
+
+	scanner := bufio.NewScanner(fifo)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		ev := Event{Type: EventType(fields[0])}
+		for _, field := range fields[1:] {
+			switch {
+			case strings.HasPrefix(field, "%"):
+				ev.PaneID = field
+			case strings.HasPrefix(field, "@"):
+				ev.WindowID = field
+			}
+		}
+		events <- ev
+	}
+}
@@ -0,0 +1,235 @@
+package tmux_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xunzhou/muxctl/pkg/tmux"
+)
+
+// These tests run muxctl against a real tmux server of their own, so they
+// catch what the fake server gets wrong about tmux
+
+// startTmux starts a throwaway tmux server with one session and returns the
+// options addressing it and the session's pane
+func startTmux(t *testing.T) ([]tmux.Option, tmux.Runner, string) {
+	t.Helper()
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux is not installed")
+	}
+	if testing.Short() {
+		t.Skip("starts a tmux server")
+	}
+
+	// Keep the tmux server of a developer running the tests out of it
+	t.Setenv("TMUX", "")
+	t.Setenv("TMUX_PANE", "")
+
+	// Each test gets its own server: one just killed may still hold its socket
+	name := fmt.Sprintf("muxctl-test-%d-%s", os.Getpid(), t.Name())
+	opts := []tmux.Option{tmux.WithSocketName(name)}
+	runner := tmux.NewRunner(opts...)
+	pane, err := runner.Run("-f", "/dev/null", "new-session", "-d", "-s", "test", "-x", "200", "-y", "50", "-P", "-F", "#{pane_id}")
+	if err != nil {
+		t.Fatalf("start tmux: %v: %s", err, pane)
+	}
+	t.Cleanup(func() { runner.Run("kill-server") })
+	return opts, runner, pane
+}
+
+// windowPanes returns the panes of the window holding pane
+func windowPanes(t *testing.T, runner tmux.Runner, pane string) []string {
+	t.Helper()
+	output, err := runner.Run("list-panes", "-t", pane, "-F", "#{pane_id}")
+	if err != nil {
+		t.Fatalf("list-panes: %v: %s", err, output)
+	}
+	return strings.Fields(output)
+}
+
+// waitForContent waits until a pane shows want
+func waitForContent(t *testing.T, mgr *tmux.Manager, pane, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		capture, err := mgr.CapturePane(pane, tmux.CaptureOptions{})
+		if err == nil && strings.Contains(capture.Content, want) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pane %s never showed %q (capture %+v, %v)", pane, want, capture, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// exerciseLayout runs a session's worth of layout changes and checks each
+func exerciseLayout(t *testing.T, mgr *tmux.Manager, runner tmux.Runner, tuiPane string) {
+	t.Helper()
+	mgr.SetResourceSpec("pod-a", tmux.ResourceSpec{Command: "cat"})
+	mgr.SetAIBackends([]tmux.AIBackend{{Name: "cat", Command: []string{"cat"}}})
+
+	if err := mgr.Setup(); err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	if got := windowPanes(t, runner, tuiPane); len(got) != 2 || got[0] != tuiPane {
+		t.Fatalf("main window panes after Setup = %v, want the TUI and the bottom pane", got)
+	}
+
+	if err := mgr.AttachResourceTerminal("pod-a"); err != nil {
+		t.Fatalf("AttachResourceTerminal: %v", err)
+	}
+	resourcePane := mgr.GetResourcePanes()["pod-a"]
+	if got := windowPanes(t, runner, tuiPane); len(got) != 2 || got[1] != resourcePane {
+		t.Fatalf("main window panes = %v, want pod-a's pane %s below the TUI", got, resourcePane)
+	}
+
+	if err := mgr.AttachAIChat(); err != nil {
+		t.Fatalf("AttachAIChat: %v", err)
+	}
+	aiChat := mgr.GetActiveAIChat()
+	aiPane := mgr.GetAIPanes()[aiChat]
+	if got := windowPanes(t, runner, tuiPane); len(got) != 2 || got[1] != aiPane {
+		t.Fatalf("main window panes = %v, want AI chat %s's pane %s below the TUI", got, aiChat, aiPane)
+	}
+	if err := mgr.SendKeys(aiPane, "hello from muxctl", "Enter"); err != nil {
+		t.Fatalf("SendKeys: %v", err)
+	}
+	waitForContent(t, mgr, aiPane, "hello from muxctl")
+
+	// The blank lines below the prompt are part of the capture
+	capture, err := mgr.CapturePane(aiPane, tmux.CaptureOptions{})
+	if err != nil {
+		t.Fatalf("CapturePane: %v", err)
+	}
+	if got := strings.Count(capture.Content, "\n") + 1; got != capture.Height {
+		t.Errorf("captured %d lines of a %d line pane: %q", got, capture.Height, capture.Content)
+	}
+
+	// The dual-slot layout shows the resource next to the AI chat
+	if dual, err := mgr.ToggleDualSlot(); err != nil || !dual {
+		t.Fatalf("ToggleDualSlot = %v, %v, want dual-slot", dual, err)
+	}
+	if err := mgr.AttachResourceTerminal("pod-a"); err != nil {
+		t.Fatalf("AttachResourceTerminal in dual-slot: %v", err)
+	}
+	if got := windowPanes(t, runner, tuiPane); len(got) != 3 || got[1] != resourcePane || got[2] != aiPane {
+		t.Fatalf("dual-slot panes = %v, want TUI, %s and %s", got, resourcePane, aiPane)
+	}
+	if dual, err := mgr.ToggleDualSlot(); err != nil || dual {
+		t.Fatalf("ToggleDualSlot = %v, %v, want single-slot", dual, err)
+	}
+	if got := windowPanes(t, runner, tuiPane); len(got) != 2 {
+		t.Fatalf("panes after merging the slots = %v, want 2", got)
+	}
+
+	mgr.Cleanup()
+	if got := windowPanes(t, runner, tuiPane); len(got) != 1 {
+		t.Errorf("panes after Cleanup = %v, want the TUI alone", got)
+	}
+}
+
+func TestIntegration(t *testing.T) {
+	opts, runner, pane := startTmux(t)
+
+	// A binding of the user's that muxctl must give back
+	if output, err := runner.Run("bind-key", "-n", "M-Enter", "display-message", "user binding"); err != nil {
+		t.Fatalf("bind-key: %v: %s", err, output)
+	}
+	// A global status-left of the user's, hidden by a session value, that
+	// muxctl must give back to the global scope it changes
+	for _, args := range [][]string{
+		{"set-option", "-g", "status-left", "[global] "},
+		{"set-option", "-t", "test", "status-left", "[session] "},
+	} {
+		if output, err := runner.Run(args...); err != nil {
+			t.Fatalf("set-option: %v: %s", err, output)
+		}
+	}
+
+	mgr, err := tmux.NewManager(append(opts, tmux.WithTarget(pane))...)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	exerciseLayout(t, mgr, runner, pane)
+
+	binding, err := runner.Run("list-keys", "-T", "root", "M-Enter")
+	if err != nil || !strings.Contains(binding, `display-message "user binding"`) {
+		t.Errorf("M-Enter after Cleanup = %q, %v, want the user's binding", binding, err)
+	}
+	if got, err := runner.Run("show-options", "-gv", "status-left"); err != nil || got != "[global] " {
+		t.Errorf("global status-left after Cleanup = %q, %v, want %q", got, err, "[global] ")
+	}
+}
+
+func TestIntegrationControlMode(t *testing.T) {
+	opts, runner, pane := startTmux(t)
+
+	control, err := tmux.NewControlRunner(pane, tmux.ServerArgs(opts...)...)
+	if err != nil {
+		t.Fatalf("NewControlRunner: %v", err)
+	}
+	defer control.Close()
+
+	mgr, err := tmux.NewManager(tmux.WithRunner(control), tmux.WithTarget(pane))
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	exerciseLayout(t, mgr, runner, pane)
+
+	if _, err := runner.Run("list-keys", "-T", "root", "M-Enter"); err == nil {
+		t.Errorf("M-Enter is still bound after Cleanup")
+	}
+}
+
+func TestIntegrationEvents(t *testing.T) {
+	opts, runner, pane := startTmux(t)
+
+	// Hooks must survive quotes, dollars and format characters in the path
+	runtimeDir := filepath.Join(t.TempDir(), `it's "#{pane_id}" $HOME`)
+	if err := os.Mkdir(runtimeDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+	mgr, err := tmux.NewManager(append(opts, tmux.WithTarget(pane))...)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if err := mgr.Setup(); err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	defer mgr.Cleanup()
+	if err := mgr.StartEvents(); err != nil {
+		t.Fatalf("StartEvents: %v", err)
+	}
+
+	doomed, err := runner.Run("new-window", "-d", "-P", "-F", "#{pane_id}", "sleep 60")
+	if err != nil {
+		t.Fatalf("new-window: %v: %s", err, doomed)
+	}
+	if output, err := runner.Run("kill-pane", "-t", doomed); err != nil {
+		t.Fatalf("kill-pane: %v: %s", err, output)
+	}
+
+	select {
+	case ev := <-mgr.Events():
+		if ev.Type == "" {
+			t.Errorf("event = %+v, want a lifecycle event", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no event arrived through the FIFO")
+	}
+
+	mgr.StopEvents()
+	for _, hook := range []string{"pane-exited", "window-unlinked", "after-kill-pane"} {
+		if output, _ := runner.Run("show-hooks", "-g", hook); strings.Contains(output, "events.fifo") {
+			t.Errorf("StopEvents left %s hook %q", hook, output)
+		}
+	}
+}
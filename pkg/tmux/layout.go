@@ -0,0 +1,68 @@
+package tmux
+
+import "fmt"
+
+// Layout configures how the main window is split between the TUI and the
+// resource and AI chat slots
+type Layout struct {
+	Horizontal     bool   // TUI left of the slots instead of above them
+	TUISize        string // TUI rows (columns when Horizontal), e.g. "12", or a percentage, e.g. "30%" ("" = half)
+	PreserveResize bool   // Keep manual resizes when panes are swapped instead of re-applying the layout
+}
+
+// WithLayout sets the orientation and size of the TUI split
+func WithLayout(layout Layout) Option {
+	return func(m *Manager) {
+		m.layout = layout
+	}
+}
+
+// mainSplit returns the split-window flag separating the TUI from the slots
+func (m *Manager) mainSplit() string {
+	if m.layout.Horizontal {
+		return "-h"
+	}
+	return "-v"
+}
+
+// slotSplit returns the split-window flag separating the resource slot from
+// the AI slot, across the TUI split
+func (m *Manager) slotSplit() string {
+	if m.layout.Horizontal {
+		return "-v"
+	}
+	return "-h"
+}
+
+// applyLayout sizes the main window: the TUI is the main pane of tmux's
+// main-horizontal (or main-vertical) layout and the slots share the rest
+func (m *Manager) applyLayout() {
+	layout, sizeOption := "main-horizontal", "main-pane-height"
+	if m.layout.Horizontal {
+		layout, sizeOption = "main-vertical", "main-pane-width"
+	}
+	size := m.layout.TUISize
+	if size == "" {
+		size = "50%"
+	}
+	m.tmuxCmd("set-window-option", "-t", m.mainWindow, sizeOption, size)
+	m.tmuxCmd("select-layout", "-t", m.mainWindow, layout)
+}
+
+// ToggleZoom shows the resource or AI chat on screen full-window, or restores
+// the layout if it already is
+// In the dual-slot layout the resource slot is zoomed unless only an AI chat
+// is shown
+func (m *Manager) ToggleZoom() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	paneID := m.bottomPane
+	if m.aiSlotPane != "" && m.activeResource == "" && m.activeAIChat != "" {
+		paneID = m.aiSlotPane
+	}
+	if err := m.tmuxCmd2("resize-pane", "-Z", "-t", paneID); err != nil {
+		return fmt.Errorf("zoom pane: %w", err)
+	}
+	return nil
+}
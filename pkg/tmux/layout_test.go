@@ -0,0 +1,181 @@
+package tmux_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/xunzhou/muxctl/pkg/tmux"
+	"github.com/xunzhou/muxctl/pkg/tmux/tmuxtest"
+)
+
+// layoutCalls returns the commands that split or size the main window, in
+// the order they were run
+func layoutCalls(srv *tmuxtest.Server) [][]string {
+	var calls [][]string
+	for _, call := range srv.Calls() {
+		switch {
+		case call[0] == "split-window", call[0] == "select-layout":
+			calls = append(calls, call)
+		case call[0] == "set-window-option" && len(call) == 5 && (call[3] == "main-pane-height" || call[3] == "main-pane-width"):
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// countLayouts returns how often the main window's layout was applied
+func countLayouts(srv *tmuxtest.Server) int {
+	n := 0
+	for _, call := range srv.Calls() {
+		if call[0] == "select-layout" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestLayout(t *testing.T) {
+	tests := []struct {
+		name      string
+		layout    tmux.Layout
+		split     string // Flag splitting the TUI from the slots
+		slotSplit string // ... and the slots from each other
+		size      []string
+		selected  string
+	}{
+		{
+			name:      "default",
+			split:     "-v",
+			slotSplit: "-h",
+			size:      []string{"main-pane-height", "50%"},
+			selected:  "main-horizontal",
+		},
+		{
+			name:      "rows",
+			layout:    tmux.Layout{TUISize: "12"},
+			split:     "-v",
+			slotSplit: "-h",
+			size:      []string{"main-pane-height", "12"},
+			selected:  "main-horizontal",
+		},
+		{
+			name:      "percentage",
+			layout:    tmux.Layout{TUISize: "30%"},
+			split:     "-v",
+			slotSplit: "-h",
+			size:      []string{"main-pane-height", "30%"},
+			selected:  "main-horizontal",
+		},
+		{
+			name:      "horizontal",
+			layout:    tmux.Layout{Horizontal: true, TUISize: "40"},
+			split:     "-h",
+			slotSplit: "-v",
+			size:      []string{"main-pane-width", "40"},
+			selected:  "main-vertical",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr, srv := newTestManager(t, tmux.WithLayout(tt.layout))
+			win := srv.PaneWindow(mgr.GetTUIPane())
+
+			// The layout, not the split, sizes the TUI
+			want := [][]string{
+				{"split-window", tt.split, "-t", mgr.GetTUIPane(), "-P", "-F", "#{pane_id}"},
+				append([]string{"set-window-option", "-t", win}, tt.size...),
+				{"select-layout", "-t", win, tt.selected},
+			}
+			got := layoutCalls(srv)
+			if len(got) != 3 || len(got[0]) < len(want[0]) {
+				t.Fatalf("Setup split and sized the main window with %q, want %q", got, want)
+			}
+			got[0] = got[0][:len(want[0])] // Drop the shell command
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Setup split and sized the main window with %q, want %q", got, want)
+			}
+
+			before := len(srv.Calls())
+			if _, err := mgr.ToggleDualSlot(); err != nil {
+				t.Fatalf("ToggleDualSlot: %v", err)
+			}
+			split := srv.Calls()[before]
+			if split[0] != "split-window" || split[1] != tt.slotSplit {
+				t.Errorf("slots were split with %q, want split-window %s", split, tt.slotSplit)
+			}
+		})
+	}
+}
+
+func TestPreserveResize(t *testing.T) {
+	for _, preserve := range []bool{false, true} {
+		mgr, srv := newTestManager(t, tmux.WithLayout(tmux.Layout{PreserveResize: preserve}))
+
+		// Each step either keeps the TUI's size, which a preserved layout
+		// leaves alone, or changes it and is always laid out again
+		for _, step := range []struct {
+			name       string
+			do         func() error
+			resizesTUI bool
+		}{
+			{"attach", func() error { return mgr.AttachResourceTerminal("pod-a") }, false},
+			{"split slots", func() error { _, err := mgr.ToggleDualSlot(); return err }, false},
+			{"refill a slot", func() error { return mgr.CloseResourcePane("pod-a") }, false},
+			{"merge slots", func() error { _, err := mgr.ToggleDualSlot(); return err }, false},
+			{"attach again", func() error { return mgr.AttachResourceTerminal("pod-b") }, false},
+			{"refill the only slot", func() error { return mgr.CloseResourcePane("pod-b") }, true},
+		} {
+			before := countLayouts(srv)
+			if err := step.do(); err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+			if got, want := countLayouts(srv) > before, step.resizesTUI || !preserve; got != want {
+				t.Errorf("PreserveResize %v: %s applied the layout = %v, want %v", preserve, step.name, got, want)
+			}
+		}
+	}
+}
+
+func TestToggleZoom(t *testing.T) {
+	mgr, srv := newTestManager(t)
+	zoomed := func() string {
+		calls := srv.Calls()
+		call := calls[len(calls)-1]
+		if call[0] != "resize-pane" || call[1] != "-Z" {
+			t.Fatalf("last call = %q, want resize-pane -Z", call)
+		}
+		return call[3]
+	}
+
+	if err := mgr.ToggleZoom(); err != nil {
+		t.Fatalf("ToggleZoom: %v", err)
+	}
+	if got := zoomed(); got != mgr.GetBottomPane() {
+		t.Errorf("zoomed %s, want the bottom pane %s", got, mgr.GetBottomPane())
+	}
+
+	// With only an AI chat shown in the dual-slot layout, the AI slot is zoomed
+	if _, err := mgr.ToggleDualSlot(); err != nil {
+		t.Fatalf("ToggleDualSlot: %v", err)
+	}
+	if err := mgr.AttachAIChat(); err != nil {
+		t.Fatalf("AttachAIChat: %v", err)
+	}
+	if err := mgr.ToggleZoom(); err != nil {
+		t.Fatalf("ToggleZoom: %v", err)
+	}
+	if got := zoomed(); got != mgr.GetAISlotPane() {
+		t.Errorf("zoomed %s, want the AI slot %s", got, mgr.GetAISlotPane())
+	}
+
+	// ... and the resource slot once a resource is shown beside it
+	if err := mgr.AttachResourceTerminal("pod-a"); err != nil {
+		t.Fatalf("AttachResourceTerminal: %v", err)
+	}
+	if err := mgr.ToggleZoom(); err != nil {
+		t.Fatalf("ToggleZoom: %v", err)
+	}
+	if got := zoomed(); got != mgr.GetBottomPane() {
+		t.Errorf("zoomed %s, want the resource slot %s", got, mgr.GetBottomPane())
+	}
+}
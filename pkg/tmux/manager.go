@@ -2,29 +2,64 @@ package tmux
 
 import (
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"sort"
+	"strconv"
 	"strings"
-	"time"
+	"sync"
 )
 
 // Manager manages the tmux layout for the terminal multiplexer
+// It is safe for concurrent use: exported methods hold mu while they read
+// or change the layout, and unexported helpers expect it to be held
 type Manager struct {
-	mainWindow      string            // Main window ID
-	tuiPane         string            // TUI pane ID (top)
-	bottomPane      string            // Currently attached bottom pane ID
-	stashWindow     string            // Stash window ID for resources
-	aiStashWindow   string            // Stash window ID for AI chats
-	resourcePanes   map[string]string // resourceID -> pane ID (tracks all resource panes)
-	aiPanes         map[string]string // aiChatID -> pane ID (tracks all AI chat panes)
-	activeResource  string            // Currently active resource ID
-	activeAIChat    string            // Currently active AI chat ID
-	stashedPanes    []string          // List of pane IDs in stash window
-	aiCounter       int               // Counter for AI chat numbering
-	userShell       string            // User's default shell
+	mu              sync.Mutex              // Guards the fields below
+	mainWindow      string                  // Main window ID
+	tuiPane         string                  // TUI pane ID (top)
+	bottomPane      string                  // Currently attached bottom pane ID (the resource slot in the dual-slot layout)
+	aiSlotPane      string                  // AI slot pane ID in the dual-slot layout ("" = single slot)
+	stashWindow     string                  // Stash window ID for resources
+	aiStashWindow   string                  // Stash window ID for AI chats
+	resourcePanes   map[string]string       // resourceID -> pane ID (tracks all resource panes)
+	aiPanes         map[string]string       // aiChatID -> pane ID (tracks all AI chat panes)
+	activeResource  string                  // Currently active resource ID
+	activeAIChat    string                  // Currently active AI chat ID
+	stashedPanes    []string                // List of pane IDs in stash window
+	aiCounter       int                     // Counter for AI chat numbering
+	userShell       string                  // User's default shell
+	runner          Runner                  // Executes tmux commands
+	target          string                  // Pane the TUI runs in ("" = tmux's current pane)
+	events          chan Event              // Pane lifecycle events from tmux hooks
+	eventsPath      string                  // FIFO the hooks write to
+	eventsFIFO      *os.File                // Open handle on eventsPath
+	resourceSpecs   map[string]ResourceSpec // resourceID -> how to start its terminal
+	aiBackends      []AIBackend             // Configured AI backends
+	aiBackend       string                  // Backend new AI chats use
+	aiChatBackends  map[string]string       // aiChatID -> backend name
+	aiSessions      map[string]string       // aiChatID -> backend session ID
+	aiTitles        map[string]string       // aiChatID -> user-chosen title
+	tabClickCommand string                  // run-shell command for status bar tab clicks ("" = not clickable)
+	theme           Theme                   // Status bar and pane border styles
+	originalOptions map[string]string       // Global option values before Setup, restored by Cleanup
+	exitMode        ExitMode                // What Cleanup leaves behind
+	serverArgs      []string                // tmux -L or -S arguments selecting the server (nil = $TMUX)
+	layout          Layout                  // Orientation and size of the TUI split
+	recordCommand   string                  // pipe-pane command for StartRecording ("" = recording unavailable)
+	recordings      map[string]string       // resourceID -> log file its pane output is piped into
+	captureFilter   func(string) string     // Applied to captured text, e.g. secret redaction (nil = none)
 }
 
+// ExitMode controls what Cleanup does with the panes muxctl created
+type ExitMode string
+
+// Exit modes
+const (
+	ExitTeardown    ExitMode = "teardown"     // Close muxctl's panes and windows, keep the session
+	ExitDetach      ExitMode = "detach"       // Leave resource and AI chat panes running for re-adoption
+	ExitKillSession ExitMode = "kill-session" // Kill the whole tmux session
+)
+
 // getUserShell returns the user's default shell from SHELL environment variable
 func getUserShell() string {
 	shell := os.Getenv("SHELL")
@@ -43,30 +78,110 @@ func getWrapperCommand(userShell string) string {
 	return fmt.Sprintf("bash -c 'while true; do %s; clear; done'", userShell)
 }
 
-// getWrapperCommandWithPS1 returns a wrapper command with custom PS1 prompt
-func getWrapperCommandWithPS1(userShell, ps1 string) string {
+// ResourceSpec describes how to start a resource's terminal
+type ResourceSpec struct {
+	Dir     string            // Working directory ("" = tmux's default)
+	Env     map[string]string // Extra environment variables
+	Command string            // Startup command ("" = the user's shell)
+	Prompt  string            // PS1 prompt ("" = "[id] $ ")
+}
+
+// getResourceCommand returns new-window arguments that run a resource's
+// startup command in an auto-respawning wrapper
+// The command is passed to tmux as separate arguments so resource IDs and
+// prompts containing spaces or quotes are not re-parsed by a shell
+func getResourceCommand(userShell, resourceID string, spec ResourceSpec) []string {
+	var args []string
+	if spec.Dir != "" {
+		args = append(args, "-c", spec.Dir)
+	}
+	envKeys := make([]string, 0, len(spec.Env))
+	for key := range spec.Env {
+		envKeys = append(envKeys, key)
+	}
+	sort.Strings(envKeys)
+	for _, key := range envKeys {
+		args = append(args, "-e", key+"="+spec.Env[key])
+	}
+
+	command := spec.Command
+	if command == "" {
+		command = userShell
+	}
+	prompt := spec.Prompt
+	if prompt == "" {
+		prompt = fmt.Sprintf("[%s] $ ", resourceID)
+	}
+
 	// For bash/zsh, set PS1. For fish, this won't work but won't break either
-	return fmt.Sprintf("bash -c 'while true; do PS1=\"%s\" %s; clear; done'", ps1, userShell)
+	script := fmt.Sprintf(resourceLoop, shellQuote(prompt), command)
+	return append(args, "bash", "-c", script)
+}
+
+// resourceLoop restarts a resource's command (%[2]s, run with PS1=%[1]s)
+// whenever it exits
+// A run shorter than 5 seconds counts as a failure: the exit status is shown
+// and the next start is delayed 1, 2, 4 then 8 seconds, and after 5 failures
+// in a row the loop waits for Enter so a broken command does not spin forever
+// A long run that exits cleanly, like a shell closed with Ctrl+D, restarts at
+// once on a cleared screen
+const resourceLoop = `fails=0
+while true; do
+	start=$SECONDS
+	PS1=%[1]s %[2]s
+	status=$?
+	if [ $((SECONDS - start)) -ge 5 ]; then fails=0; else fails=$((fails + 1)); fi
+	if [ $fails -eq 0 ]; then
+		if [ $status -eq 0 ]; then clear; else printf '\n[muxctl] exited with status %%d\n' $status; fi
+		continue
+	fi
+	if [ $fails -ge 5 ]; then
+		printf '\n[muxctl] exited with status %%d, %%d failures in a row; press Enter to restart ' $status $fails
+		read -r _
+		fails=0
+		continue
+	fi
+	delay=$((1 << (fails - 1)))
+	printf '\n[muxctl] exited with status %%d; restarting in %%ds\n' $status $delay
+	sleep $delay
+done`
+
+// shellQuote quotes s as a single POSIX shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
 // NewManager creates a new tmux manager
-func NewManager() (*Manager, error) {
+// By default every tmux command runs as its own tmux process; use WithRunner
+// to substitute another backend such as a fake server in tests
+func NewManager(opts ...Option) (*Manager, error) {
 	mgr := &Manager{
-		resourcePanes: make(map[string]string),
-		aiPanes:       make(map[string]string),
-		aiCounter:     0,
-		userShell:     getUserShell(),
+		resourcePanes:  make(map[string]string),
+		aiPanes:        make(map[string]string),
+		resourceSpecs:  make(map[string]ResourceSpec),
+		aiBackends:     []AIBackend{defaultAIBackend},
+		aiBackend:      defaultAIBackend.Name,
+		aiChatBackends: make(map[string]string),
+		aiSessions:     make(map[string]string),
+		aiTitles:       make(map[string]string),
+		recordings:     make(map[string]string),
+		aiCounter:      0,
+		userShell:      getUserShell(),
+		runner:         ExecRunner{},
+		theme:          DarkTheme,
+		exitMode:       ExitTeardown,
 	}
+	mgr.applyOptions(opts)
 
 	// Get current window
-	mainWin, err := tmuxCmd("display-message", "-p", "#{window_id}")
+	mainWin, err := mgr.tmuxCmd(mgr.targeted("display-message", "-p", "#{window_id}")...)
 	if err != nil {
 		return nil, fmt.Errorf("get window ID: %w", err)
 	}
 	mgr.mainWindow = mainWin
 
 	// Get current pane (this is the TUI pane)
-	tuiPane, err := tmuxCmd("display-message", "-p", "#{pane_id}")
+	tuiPane, err := mgr.tmuxCmd(mgr.targeted("display-message", "-p", "#{pane_id}")...)
 	if err != nil {
 		return nil, fmt.Errorf("get pane ID: %w", err)
 	}
@@ -75,10 +190,42 @@ func NewManager() (*Manager, error) {
 	return mgr, nil
 }
 
+// applyOptions applies opts and points the default runner at the selected
+// server
+func (m *Manager) applyOptions(opts []Option) {
+	for _, opt := range opts {
+		opt(m)
+	}
+	if _, ok := m.runner.(ExecRunner); ok && len(m.serverArgs) > 0 {
+		m.runner = ExecRunner{Args: m.serverArgs}
+	}
+}
+
+// targeted inserts "-t target" after the command name when a target is set
+func (m *Manager) targeted(cmd string, args ...string) []string {
+	if m.target == "" {
+		return append([]string{cmd}, args...)
+	}
+	return append([]string{cmd, "-t", m.target}, args...)
+}
+
 // Setup initializes the tmux layout
 func (m *Manager) Setup() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Save the options Setup changes before touching them
+	if err := m.saveOptions(); err != nil {
+		return err
+	}
+
 	// Rename the main window to "main"
-	tmuxCmd("rename-window", "-t", m.mainWindow, "main")
+	m.tmuxCmd("rename-window", "-t", m.mainWindow, "main")
+
+	// Re-adopt resource and AI windows left by a previous muxctl instance
+	if err := m.adoptPanes(); err != nil {
+		return err
+	}
 
 	// Count existing panes in main window
 	panes, err := m.listPanesInWindow(m.mainWindow)
@@ -87,15 +234,17 @@ func (m *Manager) Setup() error {
 	}
 
 	if len(panes) == 1 {
-		// Only TUI pane exists, create initial bottom pane with 50% split
+		// Only TUI pane exists, create initial bottom pane; applyLayout
+		// below sizes it
 		// Use a wrapper that automatically respawns shell when it exits
 		// Clear screen after each respawn for visual feedback
 		wrapperCmd := getWrapperCommand(m.userShell)
-		bottomPane, err := tmuxCmd("split-window", "-v", "-p", "50", "-t", m.tuiPane, "-P", "-F", "#{pane_id}", wrapperCmd)
+		bottomPane, err := m.tmuxCmd("split-window", m.mainSplit(), "-t", m.tuiPane, "-P", "-F", "#{pane_id}", wrapperCmd)
 		if err != nil {
 			return fmt.Errorf("create bottom pane: %w", err)
 		}
 		m.bottomPane = bottomPane
+		m.tagPane(bottomPane, kindDefault, "")
 	} else if len(panes) == 2 {
 		// Find the bottom pane (not the TUI pane)
 		for _, pane := range panes {
@@ -104,63 +253,113 @@ func (m *Manager) Setup() error {
 				break
 			}
 		}
+		// The bottom pane may be a re-adopted resource or AI chat
+		m.adoptBottomPane()
+	} else if len(panes) == 3 {
+		// A previous instance left the dual-slot layout behind
+		if err := m.adoptSlots(); err != nil {
+			return err
+		}
 	} else {
-		return fmt.Errorf("unexpected pane count: %d (expected 1 or 2)", len(panes))
+		return fmt.Errorf("unexpected pane count: %d (expected 1, 2 or 3)", len(panes))
 	}
 
-	// Apply even-vertical layout for 50/50 split
-	tmuxCmd("select-layout", "-t", m.mainWindow, "even-vertical")
-
-	// Create stash window for resources (hidden from status bar)
-	stashWin, err := tmuxCmd("new-window", "-d", "-n", "muxctl-stash", "-P", "-F", "#{window_id}", m.userShell)
-	if err != nil {
-		return fmt.Errorf("create stash window: %w", err)
+	// tmux's main-horizontal and main-vertical layouts give the first pane
+	// the main area, so that must be the TUI
+	if len(panes) > 0 && panes[0] != m.tuiPane {
+		m.tmuxCmd("swap-pane", "-s", m.tuiPane, "-t", panes[0])
 	}
-	m.stashWindow = stashWin
 
-	// Hide the stash window from the status bar
-	tmuxCmd("set-window-option", "-t", stashWin, "window-status-format", "")
-	tmuxCmd("set-window-option", "-t", stashWin, "window-status-current-format", "")
+	// Size the TUI and the slots
+	m.applyLayout()
 
-	// Create AI stash window (hidden from status bar)
-	aiStashWin, err := tmuxCmd("new-window", "-d", "-n", "muxctl-ai-stash", "-P", "-F", "#{window_id}", m.userShell)
-	if err != nil {
-		return fmt.Errorf("create AI stash window: %w", err)
+	// Create stash window for resources (hidden from status bar) unless one
+	// was re-adopted
+	if m.stashWindow == "" {
+		stashWin, err := m.tmuxCmd("new-window", "-d", "-n", "muxctl-stash", "-P", "-F", "#{window_id}", m.userShell)
+		if err != nil {
+			return fmt.Errorf("create stash window: %w", err)
+		}
+		m.stashWindow = stashWin
+		m.tagPane(stashWin, kindStash, "")
+
+		// Hide the stash window from the status bar
+		m.tmuxCmd("set-window-option", "-t", stashWin, "window-status-format", "")
+		m.tmuxCmd("set-window-option", "-t", stashWin, "window-status-current-format", "")
 	}
-	m.aiStashWindow = aiStashWin
 
-	// Hide the AI stash window from the status bar
-	tmuxCmd("set-window-option", "-t", aiStashWin, "window-status-format", "")
-	tmuxCmd("set-window-option", "-t", aiStashWin, "window-status-current-format", "")
+	// Create AI stash window (hidden from status bar) unless one was re-adopted
+	if m.aiStashWindow == "" {
+		aiStashWin, err := m.tmuxCmd("new-window", "-d", "-n", "muxctl-ai-stash", "-P", "-F", "#{window_id}", m.userShell)
+		if err != nil {
+			return fmt.Errorf("create AI stash window: %w", err)
+		}
+		m.aiStashWindow = aiStashWin
+		m.tagPane(aiStashWin, kindAIStash, "")
+
+		// Hide the AI stash window from the status bar
+		m.tmuxCmd("set-window-option", "-t", aiStashWin, "window-status-format", "")
+		m.tmuxCmd("set-window-option", "-t", aiStashWin, "window-status-current-format", "")
+	}
+	m.updateStashTracking()
 
 	// Select the main window and TUI pane
-	tmuxCmd("select-window", "-t", m.mainWindow)
-	tmuxCmd("select-pane", "-t", m.tuiPane)
+	m.tmuxCmd("select-window", "-t", m.mainWindow)
+	m.tmuxCmd("select-pane", "-t", m.tuiPane)
 
 	// Initialize status bar - tabs on left, AI chats on right
-	m.UpdateStatusBar()
+	m.updateStatusBar()
 
-	// Set status bar background to match TUI separator (xterm-256 color 39 - deep sky blue)
-	tmuxCmd("set-option", "-g", "status-style", "bg=colour39,fg=black")
+	// Style the status bar and pane borders
+	m.applyTheme()
 
 	// Hide window list from status bar
-	tmuxCmd("set-option", "-g", "window-status-format", "")
-	tmuxCmd("set-option", "-g", "window-status-current-format", "")
+	m.tmuxCmd("set-option", "-g", "window-status-format", "")
+	m.tmuxCmd("set-option", "-g", "window-status-current-format", "")
 
-	// Configure pane border colors to match TUI
-	// Inactive pane border: color 240 (dim gray) - matches TUI hint color
-	tmuxCmd("set-option", "-g", "pane-border-style", "fg=colour240")
-	// Active pane border: xterm-256 color 39 (deep sky blue) - matches TUI
-	tmuxCmd("set-option", "-g", "pane-active-border-style", "fg=colour39")
+	// Bind Alt+Enter to focus TUI pane (escape from bottom pane), saving the
+	// user's own binding for Cleanup
+	if err := m.bindKey("M-Enter", "select-pane", "-t", m.tuiPane); err != nil {
+		return err
+	}
 
-	// Bind Alt+Enter to focus TUI pane (escape from bottom pane)
-	tmuxCmd("bind-key", "-n", "M-Enter", "select-pane", "-t", m.tuiPane)
+	// Clicks on tabs, which are user ranges named after their pane ID, call
+	// back into muxctl; other clicks run the user's own binding
+	// The user's own binding is saved and put back by Cleanup
+	// #{mouse_status_range} needs tmux 3.4; older servers expand it to ""
+	// so every click falls through to the user's binding
+	if m.tabClickCommand != "" {
+		fallback, err := m.userBinding("MouseDown1Status")
+		if err != nil {
+			return err
+		}
+		args := []string{"if-shell", "-F", "#{m:[0-9]*,#{mouse_status_range}}",
+			"run-shell -b " + quoteControlArg(m.tabClickCommand)}
+		if fallback != "" {
+			args = append(args, fallback)
+		}
+		if err := m.bindKey("MouseDown1Status", args...); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
+// SetResourceSpec registers how to start the terminal for a resource
+// It takes effect the next time the resource's pane is created
+func (m *Manager) SetResourceSpec(resourceID string, spec ResourceSpec) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.resourceSpecs[resourceID] = spec
+}
+
 // AttachResourceTerminal switches the bottom pane to show the given resource
 func (m *Manager) AttachResourceTerminal(resourceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	// Get or create resource pane in stash
 	resourcePane, exists := m.resourcePanes[resourceID]
 	if !exists {
@@ -178,287 +377,439 @@ func (m *Manager) AttachResourceTerminal(resourceID string) error {
 		// This avoids tmux split limits entirely - each resource gets its own window
 		// Use auto-respawn wrapper so Ctrl+D instantly restarts shell
 		// Clear screen after each respawn for visual feedback
-		wrapperCmd := getWrapperCommandWithPS1(m.userShell, fmt.Sprintf("[%s] $ ", resourceID))
+		// Working directory, environment and startup command come from the
+		// resource's spec, if one was registered
+		wrapperArgs := getResourceCommand(m.userShell, resourceID, m.resourceSpecs[resourceID])
 		// Use a descriptive name like "Resource: pod-a" instead of "res-pod-a"
 		windowName := fmt.Sprintf("Resource: %s", resourceID)
 
-		winID, err := tmuxCmd("new-window", "-d", "-n", windowName, "-P", "-F", "#{window_id}", wrapperCmd)
+		args := append([]string{"new-window", "-d", "-n", windowName, "-P", "-F", "#{window_id}"}, wrapperArgs...)
+		winID, err := m.tmuxCmd(args...)
 		if err != nil {
 			return fmt.Errorf("create resource window: %w", err)
 		}
 
 		// Get the pane ID from the newly created window
-		newPane, err := tmuxCmd("display-message", "-t", winID, "-p", "#{pane_id}")
+		newPane, err := m.tmuxCmd("display-message", "-t", winID, "-p", "#{pane_id}")
 		if err != nil {
 			return fmt.Errorf("get pane ID: %w", err)
 		}
 
 		// Hide this window from status bar
-		tmuxCmd("set-window-option", "-t", winID, "window-status-format", "")
-		tmuxCmd("set-window-option", "-t", winID, "window-status-current-format", "")
+		m.tmuxCmd("set-window-option", "-t", winID, "window-status-format", "")
+		m.tmuxCmd("set-window-option", "-t", winID, "window-status-current-format", "")
 
 		m.resourcePanes[resourceID] = newPane
+		m.tagPane(newPane, kindResource, resourceID)
 		resourcePane = newPane
 	}
 
-	// Verify we have exactly 2 panes in main window
+	// Swap the resource pane into the bottom position
+	if err := m.swapIntoSlot(&m.bottomPane, resourcePane); err != nil {
+		return err
+	}
+
+	// Track the active resource
+	m.activeResource = resourceID
+	// Clear active AI chat since we're in resource mode, unless it has a
+	// slot of its own
+	if m.aiSlotPane == "" {
+		m.activeAIChat = ""
+	}
+
+	// Update the status bar and focus the resource terminal
+	m.focusPane(resourcePane)
+
+	return nil
+}
+
+// AttachExistingAIChat switches the bottom pane to show an existing AI chat
+func (m *Manager) AttachExistingAIChat(aiChatID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	aiPane, exists := m.aiPanes[aiChatID]
+	if !exists {
+		return fmt.Errorf("AI chat %s does not exist", aiChatID)
+	}
+
+	// Swap the AI chat pane into the bottom position or the AI slot
+	if err := m.swapIntoSlot(m.slotFor(true), aiPane); err != nil {
+		return err
+	}
+
+	// Track the active AI chat
+	m.activeAIChat = aiChatID
+	// Clear active resource since we're in AI mode, unless it has a slot of
+	// its own
+	if m.aiSlotPane == "" {
+		m.activeResource = ""
+	}
+
+	// Update the status bar and focus the AI chat
+	m.focusPane(aiPane)
+
+	return nil
+}
+
+// SwapIntoBottom swaps any pane into the bottom pane, or an AI chat into the
+// AI slot in the dual-slot layout, tracking the resource or AI chat it shows,
+// for callers that move panes by ID
+func (m *Manager) SwapIntoBottom(paneID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	resourceID, aiChatID := "", ""
+	for id, pane := range m.resourcePanes {
+		if pane == paneID {
+			resourceID = id
+		}
+	}
+	for id, pane := range m.aiPanes {
+		if pane == paneID {
+			aiChatID = id
+		}
+	}
+
+	ai := aiChatID != ""
+	if err := m.swapIntoSlot(m.slotFor(ai), paneID); err != nil {
+		return err
+	}
+
+	// The other kind stays active if it has a slot of its own
+	if ai || m.aiSlotPane == "" {
+		m.activeAIChat = aiChatID
+	}
+	if !ai || m.aiSlotPane == "" {
+		m.activeResource = resourceID
+	}
+
+	m.focusPane(paneID)
+	return nil
+}
+
+// swapIntoSlot swaps paneID into a slot of the main window, either
+// &m.bottomPane or &m.aiSlotPane
+// This is the single place where the visible pane changes, so the manager's
+// bookkeeping always matches the tmux layout
+func (m *Manager) swapIntoSlot(slot *string, paneID string) error {
+	// Verify the main window has the TUI plus one pane per slot
 	currentPanes, err := m.listPanesInWindow(m.mainWindow)
 	if err != nil {
 		return fmt.Errorf("list main window panes: %w", err)
 	}
 
-	if len(currentPanes) != 2 {
-		return fmt.Errorf("expected 2 panes in main window, found %d", len(currentPanes))
+	expected := 2
+	if m.aiSlotPane != "" {
+		expected = 3
+	}
+	if len(currentPanes) != expected {
+		return fmt.Errorf("expected %d panes in main window, found %d", expected, len(currentPanes))
 	}
 
-	// Swap the bottom pane in main window with the resource pane in stash
+	// Swap the slot's pane in main window with the target pane in its window
 	// Note: swap-pane exchanges positions but pane IDs stay with their original content
-	err = tmuxCmd2("swap-pane", "-s", m.bottomPane, "-t", resourcePane)
-	if err != nil {
-		return fmt.Errorf("swap pane failed: %w", err)
+	if paneID != *slot {
+		err = m.tmuxCmd2("swap-pane", "-s", *slot, "-t", paneID)
+		if err != nil {
+			return fmt.Errorf("swap pane failed: %w", err)
+		}
+		// The old pane now sits in paneID's window, still named after paneID
+		m.nameWindow(*slot)
 	}
 
-	// After swap: resourcePane is now in main window bottom position
-	// Update which pane ID is the current bottom pane
-	m.bottomPane = resourcePane
-
-	// Track the active resource
-	m.activeResource = resourceID
-	// Clear active AI chat since we're in resource mode
-	m.activeAIChat = ""
+	// After swap: paneID is now in the slot's position
+	// Update which pane ID fills the slot
+	*slot = paneID
 
 	// Update stashed panes list
 	m.updateStashTracking()
 
-	// Ensure layout is correct with consistent sizing (50/50 split)
-	tmuxCmd("select-layout", "-t", m.mainWindow, "even-vertical")
+	// Ensure layout is correct with consistent sizing, unless the user's own
+	// resizes are kept; swapping never changes pane sizes
+	if !m.layout.PreserveResize {
+		m.applyLayout()
+	}
+
+	return nil
+}
 
+// focusPane refreshes the status bar and focuses a pane below the TUI
+func (m *Manager) focusPane(paneID string) {
 	// Update tmux status bar with pane list
-	m.UpdateStatusBar()
+	m.updateStatusBar()
 
-	// Switch focus to the bottom pane (the resource terminal)
-	tmuxCmd("select-pane", "-t", m.bottomPane)
+	// Switch focus to the pane
+	m.tmuxCmd("select-pane", "-t", paneID)
+}
 
-	return nil
+// AttachAIChat creates a new AI chat pane and switches to it
+func (m *Manager) AttachAIChat() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.startAIChat(AIChatOptions{})
+	return err
 }
 
+// StartAIChat creates a new AI chat pane, switches to it and returns its ID
+func (m *Manager) StartAIChat(opts AIChatOptions) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-// AttachAIChat creates a new AI chat pane or switches to existing one
-func (m *Manager) AttachAIChat() error {
-	// Find the next available AI chat number (reuse numbers from closed chats)
-	aiChatID := ""
-	for i := 1; ; i++ {
-		candidateID := fmt.Sprintf("ai-%d", i)
-		if _, exists := m.aiPanes[candidateID]; !exists {
-			aiChatID = candidateID
-			break
-		}
-	}
+	return m.startAIChat(opts)
+}
 
-	// Update counter to track highest number used
-	var aiNum int
-	fmt.Sscanf(aiChatID, "ai-%d", &aiNum)
-	if aiNum > m.aiCounter {
-		m.aiCounter = aiNum
+// startAIChat implements StartAIChat; the caller holds m.mu
+func (m *Manager) startAIChat(opts AIChatOptions) (string, error) {
+	backendName := opts.Backend
+	if backendName == "" {
+		backendName = m.aiBackend
+	}
+	backend, ok := m.findAIBackend(backendName)
+	if !ok {
+		return "", fmt.Errorf("unknown AI backend %q", backendName)
+	}
+	command, session, err := aiChatCommand(backend, opts)
+	if err != nil {
+		return "", err
 	}
 
+	// Number chats monotonically so an ID never refers to a closed chat's
+	// successor; the counter is restored from adopted chats on restart
+	m.aiCounter++
+	aiNum := m.aiCounter
+	aiChatID := fmt.Sprintf("ai-%d", aiNum)
+
 	// Create a standalone window for the AI chat instead of splitting in stash window
 	// This avoids tmux split limits entirely - each AI chat gets its own window
 	// Windows are created detached (-d) and hidden from status bar
 	// Use a descriptive name like "AI Chat 1" instead of "ai-ai-1"
 	windowName := fmt.Sprintf("AI Chat %d", aiNum)
 
-	// Start with claude directly - no need for bash wrapper or send-keys
-	winID, err := tmuxCmd("new-window", "-d", "-n", windowName, "-P", "-F", "#{window_id}", "claude")
+	// Start the backend directly - no need for bash wrapper or send-keys
+	args := append([]string{"new-window", "-d", "-n", windowName, "-P", "-F", "#{window_id}"}, command...)
+	winID, err := m.tmuxCmd(args...)
 	if err != nil {
-		return fmt.Errorf("create AI chat window: %w", err)
+		return "", fmt.Errorf("create AI chat window: %w", err)
 	}
 
 	// Get the pane ID from the newly created window
-	newPane, err := tmuxCmd("display-message", "-t", winID, "-p", "#{pane_id}")
+	newPane, err := m.tmuxCmd("display-message", "-t", winID, "-p", "#{pane_id}")
 	if err != nil {
-		return fmt.Errorf("get pane ID: %w", err)
+		return "", fmt.Errorf("get pane ID: %w", err)
 	}
 
 	// Hide this window from status bar
-	tmuxCmd("set-window-option", "-t", winID, "window-status-format", "")
-	tmuxCmd("set-window-option", "-t", winID, "window-status-current-format", "")
+	m.tmuxCmd("set-window-option", "-t", winID, "window-status-format", "")
+	m.tmuxCmd("set-window-option", "-t", winID, "window-status-current-format", "")
 
 	// Track the AI pane
 	m.aiPanes[aiChatID] = newPane
+	m.aiChatBackends[aiChatID] = backend.Name
+	m.aiSessions[aiChatID] = session
+	m.tagPane(newPane, kindAI, aiChatID)
+	m.tagAIChat(newPane, backend.Name, session)
+
+	// Swap the new AI chat pane into the bottom position or the AI slot
+	if err := m.swapIntoSlot(m.slotFor(true), newPane); err != nil {
+		return "", err
+	}
 
-	// Verify we have exactly 2 panes in main window
-	currentPanes, err := m.listPanesInWindow(m.mainWindow)
-	if err != nil {
-		return fmt.Errorf("list main window panes: %w", err)
+	// Track the active AI chat
+	m.activeAIChat = aiChatID
+	// Clear active resource since we're in AI mode, unless it has a slot of
+	// its own
+	if m.aiSlotPane == "" {
+		m.activeResource = ""
 	}
 
-	if len(currentPanes) != 2 {
-		return fmt.Errorf("expected 2 panes in main window, found %d", len(currentPanes))
+	// Update the status bar and focus the AI chat
+	m.focusPane(newPane)
+
+	return aiChatID, nil
+}
+
+// SendToAIChat types a message into an AI chat and submits it
+func (m *Manager) SendToAIChat(aiChatID, message string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	aiPane, exists := m.aiPanes[aiChatID]
+	if !exists {
+		return fmt.Errorf("AI chat %s does not exist", aiChatID)
 	}
 
-	// Swap the bottom pane in main window with the AI chat pane
-	err = tmuxCmd2("swap-pane", "-s", m.bottomPane, "-t", newPane)
-	if err != nil {
-		return fmt.Errorf("swap pane failed: %w", err)
+	// -l sends the text literally so words like "Enter" are not read as keys
+	if err := m.tmuxCmd2("send-keys", "-t", aiPane, "-l", message); err != nil {
+		return fmt.Errorf("send message: %w", err)
 	}
+	if err := m.tmuxCmd2("send-keys", "-t", aiPane, "Enter"); err != nil {
+		return fmt.Errorf("submit message: %w", err)
+	}
+	return nil
+}
 
-	// After swap: newPane is now in main window bottom position
-	m.bottomPane = newPane
+// SendKeys sends keys to a pane using send-keys syntax, e.g. "ls" "Enter"
+func (m *Manager) SendKeys(paneID string, keys ...string) error {
+	args := append([]string{"send-keys", "-t", paneID}, keys...)
+	if err := m.tmuxCmd2(args...); err != nil {
+		return fmt.Errorf("send keys: %w", err)
+	}
+	return nil
+}
 
-	// Track the active AI chat
-	m.activeAIChat = aiChatID
-	// Clear active resource since we're in AI mode
-	m.activeResource = ""
+// CloseAIChat kills the pane for a given AI chat
+func (m *Manager) CloseAIChat(aiChatID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	// Update stashed panes list
-	m.updateStashTracking()
+	aiPane, exists := m.aiPanes[aiChatID]
+	if !exists {
+		return fmt.Errorf("AI chat %s does not exist", aiChatID)
+	}
 
-	// Ensure layout is correct with consistent sizing (50/50 split)
-	tmuxCmd("select-layout", "-t", m.mainWindow, "even-vertical")
+	if err := m.tmuxCmd2("kill-pane", "-t", aiPane); err != nil {
+		return fmt.Errorf("kill AI chat pane: %w", err)
+	}
 
-	// Update tmux status bar with pane list
-	m.UpdateStatusBar()
+	// A visible chat leaves its slot of the main window empty
+	if aiPane == *m.slotFor(true) {
+		if _, err := m.refillSlot(aiPane, m.userShell); err != nil {
+			return err
+		}
+	}
+	if aiChatID == m.activeAIChat {
+		m.activeAIChat = ""
+	}
 
-	// Switch focus to the bottom pane (the AI chat)
-	tmuxCmd("select-pane", "-t", m.bottomPane)
+	// Remove from tracking
+	m.forgetAIChat(aiChatID)
+
+	// Update status bar
+	m.updateStatusBar()
 
 	return nil
 }
 
-// ShowAIChooser displays a unified fzf popup to select and swap AI chats or resources
-func (m *Manager) ShowAIChooser() {
-	// Build lists of both AI chats and resources with their pane IDs
-	var aiList []string
-	var paneMap []string // Maps "type:id" to pane ID
-	for aiID, paneID := range m.aiPanes {
-		aiList = append(aiList, "ai:"+aiID)
-		paneMap = append(paneMap, fmt.Sprintf("ai:%s:%s", aiID, paneID))
-	}
-	sort.Strings(aiList)
+// forgetAIChat drops an AI chat from tracking
+func (m *Manager) forgetAIChat(aiChatID string) {
+	delete(m.aiPanes, aiChatID)
+	delete(m.aiChatBackends, aiChatID)
+	delete(m.aiSessions, aiChatID)
+	delete(m.aiTitles, aiChatID)
+}
 
-	var resList []string
-	for resID, paneID := range m.resourcePanes {
-		resList = append(resList, "res:"+resID)
-		paneMap = append(paneMap, fmt.Sprintf("res:%s:%s", resID, paneID))
-	}
-	sort.Strings(resList)
-
-	if len(aiList) == 0 && len(resList) == 0 {
-		return // Nothing to show
-	}
-
-	// Combine both lists for display
-	allItems := append(aiList, resList...)
-
-	// Create a script with fzf that allows toggling between AI and Resources
-	// Ctrl-A shows only AI chats, Ctrl-R shows only resources, Ctrl-T shows all
-	script := fmt.Sprintf(`
-		# Create temp files - one for display, one for the pane mapping
-		tmpfile=$(mktemp)
-		mapfile=$(mktemp)
-		printf '%%s\n' %s > "$tmpfile"
-		printf '%%s\n' %s > "$mapfile"
-
-		# Use fzf with toggle bindings
-		selected=$(cat "$tmpfile" | fzf \
-			--prompt='Select (^A=AI ^R=Res ^T=All): ' \
-			--height=60%% \
-			--reverse \
-			--border \
-			--header='AI Chats & Resources' \
-			--bind "ctrl-a:reload(awk /^ai:/ $tmpfile)" \
-			--bind "ctrl-r:reload(awk /^res:/ $tmpfile)" \
-			--bind "ctrl-t:reload(cat $tmpfile)")
-
-		if [ -n "$selected" ]; then
-			type=$(echo "$selected" | cut -d: -f1)
-			id=$(echo "$selected" | cut -d: -f2)
-
-			# Look up the pane ID from the map file
-			# Map format is "type:id:paneID"
-			pane_id=$(grep "^${type}:${id}:" "$mapfile" | cut -d: -f3)
-
-			if [ -n "$pane_id" ]; then
-				# Get the current bottom pane in the main window dynamically
-				current_bottom=$(tmux list-panes -t main -F '#{pane_id} #{pane_index}' | grep ' 1$' | cut -d' ' -f1)
-
-				# Only swap if the selected pane is not already the bottom pane
-				if [ "$pane_id" != "$current_bottom" ]; then
-					# Swap the pane with the bottom pane in main window
-					tmux swap-pane -s "$current_bottom" -t "$pane_id"
-				fi
-
-				# Select the main window
-				tmux select-window -t main
-
-				# Focus the bottom pane by position (index 1)
-				tmux select-pane -t main.1
-
-				# Output the selected type and id so Go can update state
-				echo "$type:$id"
-			fi
-		fi
-
-		rm -f "$tmpfile" "$mapfile"
-	`, strings.Join(allItems, " "), strings.Join(paneMap, " "))
-
-	// Show the popup with the script
-	// Note: display-popup with -E doesn't capture output well
-	// Instead, write output to a temp file
-	tmpfile := fmt.Sprintf("/tmp/muxctl-selector-%d", time.Now().Unix())
-	scriptWithOutput := strings.Replace(script, `echo "$type:$id"`, fmt.Sprintf(`echo "$type:$id" > %s`, tmpfile), 1)
-
-	// Always use bash for the fzf popup script (it has bash syntax)
-	tmuxCmd("display-popup", "-E", "-w", "60%", "-h", "60%", "bash", "-c", scriptWithOutput)
-
-	// Read the output from the temp file
-	output, err := os.ReadFile(tmpfile)
-	os.Remove(tmpfile) // Clean up
-
-	if err == nil && len(output) > 0 {
-		selection := strings.TrimSpace(string(output))
-		parts := strings.Split(selection, ":")
-		if len(parts) == 2 {
-			selectedType := parts[0]
-			selectedID := parts[1]
-
-			if selectedType == "ai" {
-				m.activeAIChat = selectedID
-				m.activeResource = ""
-			} else if selectedType == "res" {
-				m.activeResource = selectedID
-				m.activeAIChat = ""
-			}
+// RenameAIChat gives an AI chat a title shown in its status bar tab, window
+// name and pane title; an empty title restores the numbered name
+func (m *Manager) RenameAIChat(aiChatID, title string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-			// After the swap in the bash script, the selected pane is now at position 1 (bottom)
-			// We need to get the actual pane ID at that position
-			panes, err := m.listPanesInWindow(m.mainWindow)
-			if err == nil && len(panes) >= 2 {
-				// The bottom pane is the one that's not the TUI pane
-				for _, pane := range panes {
-					if pane != m.tuiPane {
-						m.bottomPane = pane
-						break
-					}
-				}
-			}
+	aiPane, exists := m.aiPanes[aiChatID]
+	if !exists {
+		return fmt.Errorf("AI chat %s does not exist", aiChatID)
+	}
+	title = strings.TrimSpace(title)
+
+	// The title is stored on the pane so it survives swaps and restarts
+	if title == "" {
+		m.tmuxCmd("set-option", "-p", "-u", "-t", aiPane, optTitle)
+		delete(m.aiTitles, aiChatID)
+	} else {
+		if err := m.tmuxCmd2("set-option", "-p", "-t", aiPane, optTitle, title); err != nil {
+			return fmt.Errorf("set AI chat title: %w", err)
+		}
+		m.aiTitles[aiChatID] = title
+	}
+	m.tmuxCmd("select-pane", "-t", aiPane, "-T", m.aiChatName(aiChatID))
 
-			// Update stashed panes tracking
-			m.updateStashTracking()
+	// Rename the window holding the chat, unless it is currently swapped
+	// into the main window
+	m.nameWindow(aiPane)
 
-			// Update status bar to reflect the change
-			m.UpdateStatusBar()
+	m.updateStatusBar()
+	return nil
+}
 
-			// Ensure the selected pane is focused
-			tmuxCmd("select-window", "-t", m.mainWindow)
-			tmuxCmd("select-pane", "-t", m.bottomPane)
+// windowName returns the name for the hidden window holding a pane: its
+// resource or AI chat name, or "" for a pane that belongs to neither
+func (m *Manager) windowName(paneID string) string {
+	for resID, id := range m.resourcePanes {
+		if id == paneID {
+			return fmt.Sprintf("Resource: %s", resID)
 		}
 	}
+	for aiID, id := range m.aiPanes {
+		if id == paneID {
+			return m.aiChatName(aiID)
+		}
+	}
+	return ""
+}
+
+// nameWindow renames the hidden window a pane sits in after it, so window
+// lists and choose-tree show what the window holds
+// The main window and shared windows such as the stash keep their names
+func (m *Manager) nameWindow(paneID string) {
+	output, err := m.tmuxCmd("display-message", "-t", paneID, "-p", "#{window_id} #{window_panes}")
+	if err != nil {
+		return
+	}
+	winID, panes, _ := strings.Cut(output, " ")
+	if winID == m.mainWindow || panes != "1" {
+		return
+	}
+	name := m.windowName(paneID)
+	if name == "" {
+		name = "shell"
+	}
+	m.tmuxCmd("rename-window", "-t", winID, name)
+}
+
+// aiChatName returns the window name for an AI chat, e.g. "ai: oom triage"
+// or "AI Chat 3" for an untitled chat
+func (m *Manager) aiChatName(aiChatID string) string {
+	if title := m.aiTitles[aiChatID]; title != "" {
+		return fmt.Sprintf("%s: %s", m.aiPrefix(aiChatID), title)
+	}
+	return fmt.Sprintf("AI Chat %s", strings.TrimPrefix(aiChatID, "ai-"))
+}
+
+// ResizeAIChat gives an AI chat on screen widthPercent of its window
+// The chat's width is set where it shares a row with another pane: the
+// resource slot in the dual-slot layout or the TUI in the horizontal layout
+// Below a full-width TUI the percentage sets the slot's height instead
+func (m *Manager) ResizeAIChat(aiChatID string, widthPercent int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	aiPane, exists := m.aiPanes[aiChatID]
+	if !exists {
+		return fmt.Errorf("AI chat %s does not exist", aiChatID)
+	}
+	if widthPercent <= 0 || widthPercent > 100 {
+		return fmt.Errorf("width %d%% out of range", widthPercent)
+	}
+	if aiPane != *m.slotFor(true) {
+		return fmt.Errorf("AI chat %s is not on screen", aiChatID)
+	}
+
+	axis := "-x"
+	if m.aiSlotPane == "" && !m.layout.Horizontal {
+		axis = "-y"
+	}
+	if err := m.tmuxCmd2("resize-pane", "-t", aiPane, axis, fmt.Sprintf("%d%%", widthPercent)); err != nil {
+		return fmt.Errorf("resize AI chat pane: %w", err)
+	}
+	return nil
 }
 
 // CloseResourcePane kills the pane for a given resource
 func (m *Manager) CloseResourcePane(resourceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	// Get the pane ID for this resource
 	paneID, exists := m.resourcePanes[resourceID]
 	if !exists {
@@ -468,36 +819,33 @@ func (m *Manager) CloseResourcePane(resourceID string) error {
 	// If this is the active resource, we need to handle it specially
 	if resourceID == m.activeResource {
 		// Kill the bottom pane
-		err := tmuxCmd2("kill-pane", "-t", paneID)
+		err := m.tmuxCmd2("kill-pane", "-t", paneID)
 		if err != nil {
 			return fmt.Errorf("kill active pane: %w", err)
 		}
 
 		// Create a new placeholder bottom pane
-		newBottomPane, err := tmuxCmd("split-window", "-v", "-p", "50", "-t", m.tuiPane, "-P", "-F", "#{pane_id}", m.userShell)
-		if err != nil {
-			return fmt.Errorf("create replacement pane: %w", err)
+		if _, err := m.refillSlot(paneID, m.userShell); err != nil {
+			return err
 		}
-
-		m.bottomPane = newBottomPane
 		m.activeResource = ""
-		tmuxCmd("select-layout", "-t", m.mainWindow, "even-vertical")
 	} else {
 		// Resource is in stash, just kill it
-		err := tmuxCmd2("kill-pane", "-t", paneID)
+		err := m.tmuxCmd2("kill-pane", "-t", paneID)
 		if err != nil {
 			return fmt.Errorf("kill stashed pane: %w", err)
 		}
 	}
 
-	// Remove from tracking
+	// Remove from tracking; killing the pane also closed any recording pipe
 	delete(m.resourcePanes, resourceID)
+	delete(m.recordings, resourceID)
 
 	// Update stash tracking
 	m.updateStashTracking()
 
 	// Update status bar
-	m.UpdateStatusBar()
+	m.updateStatusBar()
 
 	return nil
 }
@@ -505,7 +853,7 @@ func (m *Manager) CloseResourcePane(resourceID string) error {
 // cleanupDeadPanes removes any panes from tracking that no longer exist
 func (m *Manager) cleanupDeadPanes() {
 	// Get all existing pane IDs
-	allPanes, err := tmuxCmd("list-panes", "-a", "-F", "#{pane_id}")
+	allPanes, err := m.tmuxCmd("list-panes", "-a", "-F", "#{pane_id}")
 	if err != nil {
 		return
 	}
@@ -521,13 +869,14 @@ func (m *Manager) cleanupDeadPanes() {
 	for resID, paneID := range m.resourcePanes {
 		if !existingPanes[paneID] {
 			delete(m.resourcePanes, resID)
+			delete(m.recordings, resID)
 		}
 	}
 
 	// Clean up AI panes that no longer exist
 	for aiID, paneID := range m.aiPanes {
 		if !existingPanes[paneID] {
-			delete(m.aiPanes, aiID)
+			m.forgetAIChat(aiID)
 			// If this was the active AI chat, clear it
 			if aiID == m.activeAIChat {
 				m.activeAIChat = ""
@@ -535,6 +884,28 @@ func (m *Manager) cleanupDeadPanes() {
 		}
 	}
 
+	// In the dual-slot layout refill whichever slot lost its pane, or fall
+	// back to a single slot if both did
+	if m.aiSlotPane != "" {
+		bottomDead, aiSlotDead := !existingPanes[m.bottomPane], !existingPanes[m.aiSlotPane]
+		switch {
+		case bottomDead && aiSlotDead:
+			m.aiSlotPane = ""
+		case aiSlotDead:
+			if _, err := m.refillSlot(m.aiSlotPane, getWrapperCommand(m.userShell)); err == nil {
+				m.activeAIChat = ""
+			}
+			return
+		case bottomDead:
+			if _, err := m.refillSlot(m.bottomPane, getWrapperCommand(m.userShell)); err == nil {
+				m.activeResource = ""
+			}
+			return
+		default:
+			return
+		}
+	}
+
 	// Check if the current bottom pane is dead (e.g., AI chat exited and auto-swapped, or user pressed Ctrl+D)
 	if !existingPanes[m.bottomPane] {
 		// The bottom pane is dead, check the main window pane count
@@ -543,13 +914,9 @@ func (m *Manager) cleanupDeadPanes() {
 			if len(mainPanes) == 1 {
 				// Only TUI pane left - the default pane died (user pressed Ctrl+D)
 				// Recreate the default bottom pane with auto-respawn wrapper
-				wrapperCmd := getWrapperCommand(m.userShell)
-				newBottomPane, err := tmuxCmd("split-window", "-v", "-p", "50", "-t", m.tuiPane, "-P", "-F", "#{pane_id}", wrapperCmd)
-				if err == nil {
-					m.bottomPane = newBottomPane
+				if _, err := m.refillSlot(m.bottomPane, getWrapperCommand(m.userShell)); err == nil {
 					m.activeResource = ""
 					m.activeAIChat = ""
-					tmuxCmd("select-layout", "-t", m.mainWindow, "even-vertical")
 				}
 			} else if len(mainPanes) == 2 {
 				// Two panes exist, find which one is the bottom pane
@@ -574,12 +941,22 @@ func (m *Manager) updateStashTracking() {
 
 // UpdateStatusBar updates the tmux status bar with clickable pane tabs
 func (m *Manager) UpdateStatusBar() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.updateStatusBar()
+}
+
+// updateStatusBar implements UpdateStatusBar; the caller holds m.mu
+func (m *Manager) updateStatusBar() {
 	// Clean up any dead panes before updating status
 	m.cleanupDeadPanes()
 
-	// Determine which context is active for dimming
-	inResourceMode := m.activeResource != ""
-	inAIMode := m.activeAIChat != ""
+	// Determine which context is active for dimming; with a slot each,
+	// resources and AI chats are both on screen and neither is dimmed
+	dualSlot := m.aiSlotPane != ""
+	inResourceMode := m.activeResource != "" && !dualSlot
+	inAIMode := m.activeAIChat != "" && !dualSlot
 
 	// Build pane list with clickable elements using status-format syntax
 	var tabParts []string
@@ -602,12 +979,12 @@ func (m *Manager) UpdateStatusBar() {
 
 	// Add bullet tab to represent the default shell (always present)
 	var defTab string
-	if m.activeResource == "" && m.activeAIChat == "" {
+	if m.activeResource == "" && (m.activeAIChat == "" || dualSlot) {
 		// Default shell is active - highlight it
-		defTab = " #[reverse]•#[noreverse] "
+		defTab = " " + styleTab(m.theme.ActiveTab, "•") + " "
 	} else {
 		// Default shell is in background - dim it
-		defTab = " #[dim]•#[nodim] "
+		defTab = " " + styleTab(m.theme.DimTab, "•") + " "
 	}
 	tabParts = append(tabParts, defTab)
 
@@ -643,21 +1020,31 @@ func (m *Manager) UpdateStatusBar() {
 		// Format the tab with visual styling
 		var tabText string
 
+		// tmux would expand a "#" in the ID as a format
+		label := strings.ReplaceAll(resID, "#", "##")
+
+		// Mark resources whose output is being recorded; the marker is
+		// styled on its own since styleTab resets the style after it
+		marker := ""
+		if _, ok := m.recordings[resID]; ok {
+			marker = styleTab(m.theme.RecordingTab, "●")
+		}
+
 		if resID == m.activeResource {
-			// Active tab: reverse video (inverted colors)
-			tabText = fmt.Sprintf(" #[reverse]%s#[noreverse] ", resID)
+			// Active tab: highlighted
+			tabText = fmt.Sprintf(" %s%s ", marker, styleTab(m.theme.ActiveTab, label))
 		} else {
 			// Inactive tab: default styling with context-aware dimming
 			if inAIMode {
 				// Dim resource tabs when AI is active
-				tabText = fmt.Sprintf(" #[dim]%s#[nodim] ", resID)
+				tabText = fmt.Sprintf(" %s%s ", marker, styleTab(m.theme.DimTab, label))
 			} else {
 				// Normal brightness when resource active or default pane
-				tabText = fmt.Sprintf(" %s ", resID)
+				tabText = fmt.Sprintf(" %s%s ", marker, styleTab(m.theme.InactiveTab, label))
 			}
 		}
 
-		tabParts = append(tabParts, tabText)
+		tabParts = append(tabParts, clickableTab(m.resourcePanes[resID], tabText))
 	}
 
 	// If there are more tabs than displayed, add a count indicator
@@ -677,23 +1064,17 @@ func (m *Manager) UpdateStatusBar() {
 	}
 
 	// Set tabs on the left side
-	tmuxCmd("set-option", "-g", "status-left-length", fmt.Sprintf("%d", statusLeftLen))
-	tmuxCmd("set-option", "-g", "status-left", statusContent)
+	m.tmuxCmd("set-option", "-g", "status-left-length", fmt.Sprintf("%d", statusLeftLen))
+	m.tmuxCmd("set-option", "-g", "status-left", statusContent)
 
 	// Build AI chat list for the right side
 	var aiParts []string
-	var aiChatIDs []string
-	for aiID := range m.aiPanes {
-		aiChatIDs = append(aiChatIDs, aiID)
-	}
+	aiChatIDs := m.sortedAIChatIDs()
 
-	// Sort AI chats
-	for i := 0; i < len(aiChatIDs); i++ {
-		for j := i + 1; j < len(aiChatIDs); j++ {
-			if aiChatIDs[i] > aiChatIDs[j] {
-				aiChatIDs[i], aiChatIDs[j] = aiChatIDs[j], aiChatIDs[i]
-			}
-		}
+	// Tabs are labelled with their position, which keys 1-9 select
+	tabIndex := make(map[string]int, len(aiChatIDs))
+	for i, aiID := range aiChatIDs {
+		tabIndex[aiID] = i + 1
 	}
 
 	// Create AI chat tabs
@@ -724,32 +1105,40 @@ func (m *Manager) UpdateStatusBar() {
 		}
 	}
 
-	// Add "ai" prefix before the tab numbers
-	if len(displayAIIDs) > 0 {
-		aiParts = append(aiParts, "ai")
-	}
-
+	prefix := ""
 	for _, aiID := range displayAIIDs {
-		// Extract just the number from "ai-N"
-		aiNum := strings.TrimPrefix(aiID, "ai-")
+		// Start each backend's group with its prefix, e.g. "ai 1 2 llm 3"
+		if aiPrefix := m.aiPrefix(aiID); aiPrefix != prefix {
+			if prefix != "" {
+				aiParts = append(aiParts, " ")
+			}
+			prefix = aiPrefix
+			aiParts = append(aiParts, prefix)
+		}
+
+		// Show the tab's position, followed by the chat's title if it has one
+		aiNum := strconv.Itoa(tabIndex[aiID])
+		if title := m.aiTitles[aiID]; title != "" {
+			aiNum += ":" + strings.ReplaceAll(title, "#", "##")
+		}
 
 		// Format the tab with visual styling
 		var aiTab string
 
 		if aiID == m.activeAIChat {
-			// Active tab: reverse video (inverted colors)
-			aiTab = fmt.Sprintf(" #[reverse]%s#[noreverse]", aiNum)
+			// Active tab: highlighted
+			aiTab = " " + styleTab(m.theme.ActiveTab, aiNum)
 		} else {
 			// Inactive tab: default styling with context-aware dimming
 			if inResourceMode {
 				// Dim AI tabs when resource is active
-				aiTab = fmt.Sprintf(" #[dim]%s#[nodim]", aiNum)
+				aiTab = " " + styleTab(m.theme.DimTab, aiNum)
 			} else {
 				// Normal brightness when AI active or default pane
-				aiTab = fmt.Sprintf(" %s", aiNum)
+				aiTab = " " + styleTab(m.theme.InactiveTab, aiNum)
 			}
 		}
-		aiParts = append(aiParts, aiTab)
+		aiParts = append(aiParts, clickableTab(m.aiPanes[aiID], aiTab))
 	}
 
 	// If there are more AI chat tabs than displayed, add a count indicator
@@ -768,22 +1157,58 @@ func (m *Manager) UpdateStatusBar() {
 	}
 
 	// Set AI chats on the right side
-	tmuxCmd("set-option", "-g", "status-right-length", fmt.Sprintf("%d", statusRightLen))
-	tmuxCmd("set-option", "-g", "status-right", aiStatusContent)
+	m.tmuxCmd("set-option", "-g", "status-right-length", fmt.Sprintf("%d", statusRightLen))
+	m.tmuxCmd("set-option", "-g", "status-right", aiStatusContent)
+}
+
+// SessionName returns the name of the tmux session the TUI runs in
+func (m *Manager) SessionName() (string, error) {
+	return m.tmuxCmd(m.targeted("display-message", "-p", "#{session_name}")...)
+}
+
+// SetSessionOption sets a user option on the manager's session, e.g. to
+// publish a socket path; an empty value unsets it
+func (m *Manager) SetSessionOption(name, value string) error {
+	if value == "" {
+		return m.tmuxCmd2(m.targeted("set-option", "-u", name)...)
+	}
+	return m.tmuxCmd2(m.targeted("set-option", name, value)...)
+}
+
+// ServerSocket returns the socket path of the tmux server the manager talks to
+func (m *Manager) ServerSocket() (string, error) {
+	return m.tmuxCmd("display-message", "-p", "#{socket_path}")
+}
+
+// clickableTab wraps a status bar tab in a user range named after its pane,
+// which tmux reports as #{mouse_status_range} when the tab is clicked
+// The range is the pane number without its "%" since status-left and
+// status-right are passed through strftime before styles are parsed
+func clickableTab(paneID, tab string) string {
+	return fmt.Sprintf("#[range=user|%s]%s#[norange]", strings.TrimPrefix(paneID, "%"), tab)
 }
 
 // GetActiveResource returns the currently active resource ID
 func (m *Manager) GetActiveResource() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	return m.activeResource
 }
 
 // GetActiveAIChat returns the currently active AI chat ID
 func (m *Manager) GetActiveAIChat() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	return m.activeAIChat
 }
 
 // GetStashedResources returns a list of resource IDs that are in the stash
 func (m *Manager) GetStashedResources() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	var stashed []string
 	for resID, paneID := range m.resourcePanes {
 		if resID != m.activeResource {
@@ -801,6 +1226,9 @@ func (m *Manager) GetStashedResources() []string {
 
 // GetPaneInfo returns detailed info about pane locations
 func (m *Manager) GetPaneInfo() map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	info := make(map[string]string)
 
 	for resID, paneID := range m.resourcePanes {
@@ -826,29 +1254,82 @@ func (m *Manager) GetPaneInfo() map[string]string {
 	return info
 }
 
-// GetResourcePanes returns the map of resource ID to pane ID
+// GetResourcePanes returns a copy of the map of resource ID to pane ID
 func (m *Manager) GetResourcePanes() map[string]string {
-	return m.resourcePanes
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return copyPanes(m.resourcePanes)
 }
 
-// GetAIPanes returns the map of AI chat ID to pane ID
+// GetAIPanes returns a copy of the map of AI chat ID to pane ID
 func (m *Manager) GetAIPanes() map[string]string {
-	return m.aiPanes
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return copyPanes(m.aiPanes)
+}
+
+// copyPanes copies an ID -> pane ID map so callers never see later updates
+func copyPanes(panes map[string]string) map[string]string {
+	copied := make(map[string]string, len(panes))
+	for id, paneID := range panes {
+		copied[id] = paneID
+	}
+	return copied
+}
+
+// PaneFor returns the pane of a resource or AI chat
+func (m *Manager) PaneFor(id string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if paneID, exists := m.resourcePanes[id]; exists {
+		return paneID, nil
+	}
+	if paneID, exists := m.aiPanes[id]; exists {
+		return paneID, nil
+	}
+	return "", fmt.Errorf("%s has no pane", id)
+}
+
+// IDForPane returns the resource or AI chat shown in a pane
+func (m *Manager) IDForPane(paneID string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for resID, id := range m.resourcePanes {
+		if id == paneID {
+			return resID, true
+		}
+	}
+	for aiID, id := range m.aiPanes {
+		if id == paneID {
+			return aiID, true
+		}
+	}
+	return "", false
 }
 
 // GetTUIPane returns the TUI pane ID
 func (m *Manager) GetTUIPane() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	return m.tuiPane
 }
 
 // GetBottomPane returns the current bottom pane ID
 func (m *Manager) GetBottomPane() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	return m.bottomPane
 }
 
 // listPanesInWindow returns pane IDs in a window
 func (m *Manager) listPanesInWindow(windowID string) ([]string, error) {
-	output, err := tmuxCmd("list-panes", "-t", windowID, "-F", "#{pane_id}")
+	output, err := m.tmuxCmd("list-panes", "-t", windowID, "-F", "#{pane_id}")
 	if err != nil {
 		return nil, err
 	}
@@ -862,25 +1343,18 @@ func (m *Manager) listPanesInWindow(windowID string) ([]string, error) {
 
 // GetActivePane returns the currently active pane ID (bottom pane in main window)
 func (m *Manager) GetActivePane() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.bottomPane == "" {
 		return "", fmt.Errorf("no active pane")
 	}
 	return m.bottomPane, nil
 }
 
-// CapturePane captures the content of a pane
-func (m *Manager) CapturePane(paneID string, opts interface{}) (string, error) {
-	args := []string{"capture-pane", "-t", paneID, "-p"}
-	output, err := tmuxCmd(args...)
-	if err != nil {
-		return "", fmt.Errorf("failed to capture pane: %w", err)
-	}
-	return output, nil
-}
-
 // ListPanes returns all pane IDs in the session
 func (m *Manager) ListPanes() ([]string, error) {
-	output, err := tmuxCmd("list-panes", "-a", "-F", "#{pane_id}")
+	output, err := m.tmuxCmd("list-panes", "-a", "-F", "#{pane_id}")
 	if err != nil {
 		return nil, err
 	}
@@ -892,45 +1366,83 @@ func (m *Manager) ListPanes() ([]string, error) {
 	return strings.Split(strings.TrimSpace(output), "\n"), nil
 }
 
-// Cleanup removes the stash windows and resets status bar, then kills the tmux session
+// GetExitMode returns what Cleanup will do with muxctl's panes
+func (m *Manager) GetExitMode() ExitMode {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.exitMode
+}
+
+// Cleanup resets the status bar, key bindings and hooks, and then closes
+// muxctl's panes, leaves them running or kills the session depending on the
+// exit mode
 func (m *Manager) Cleanup() {
-	if m.stashWindow != "" {
-		tmuxCmd("kill-window", "-t", m.stashWindow)
-	}
-	if m.aiStashWindow != "" {
-		tmuxCmd("kill-window", "-t", m.aiStashWindow)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Close resource, AI chat and stash panes unless they are kept for the
+	// next muxctl or go down with the session anyway
+	if m.exitMode != ExitDetach && m.exitMode != ExitKillSession {
+		m.killOwnedPanes()
 	}
-	// Restore default status bar settings
-	tmuxCmd("set-option", "-g", "status-left", "[#{session_name}] ")
-	tmuxCmd("set-option", "-g", "status-right", "#{?window_bigger,[#{window_offset_x}#,#{window_offset_y}] ,}\"#{=21:pane_title}\" %H:%M %d-%b-%y")
-	tmuxCmd("set-option", "-g", "window-status-format", "#I:#W#F")
-	tmuxCmd("set-option", "-g", "window-status-current-format", "#I:#W#F")
 
-	// Restore default pane border colors
-	tmuxCmd("set-option", "-g", "pane-border-style", "default")
-	tmuxCmd("set-option", "-g", "pane-active-border-style", "default")
+	// Restore the status bar and pane border options saved by Setup
+	m.restoreOptions()
+
+	// Give Alt+Enter back to the user's binding, if they have not rebound
+	// it meanwhile
+	m.restoreKey("M-Enter")
+
+	// Give status bar clicks back to the user's binding, if they have not
+	// rebound them meanwhile
+	m.restoreKey("MouseDown1Status")
+
+	// Remove lifecycle hooks
+	m.stopEvents()
 
-	// Unbind Alt+Enter
-	tmuxCmd("unbind-key", "-n", "M-Enter")
+	if m.exitMode == ExitKillSession {
+		// Kill the current tmux session
+		m.tmuxCmd(m.targeted("kill-session")...)
+	} else {
+		// Let tmux name the main window again
+		m.tmuxCmd("set-window-option", "-u", "-t", m.mainWindow, "automatic-rename")
+		m.tmuxCmd("set-window-option", "-u", "-t", m.mainWindow, "main-pane-height")
+		m.tmuxCmd("set-window-option", "-u", "-t", m.mainWindow, "main-pane-width")
+	}
+
+	// Shut down a persistent connection such as control mode
+	if closer, ok := m.runner.(io.Closer); ok {
+		closer.Close()
+	}
+}
 
-	// Kill the current tmux session
-	tmuxCmd("kill-session")
+// Subscribe returns asynchronous tmux notifications when the runner supports
+// them (control mode); otherwise it returns a nil channel
+func (m *Manager) Subscribe() (<-chan Notification, func()) {
+	if notifier, ok := m.runner.(Notifier); ok {
+		return notifier.Subscribe()
+	}
+	return nil, func() {}
 }
 
 // TmuxCmd runs a tmux command and returns stdout (exported for use by other packages)
 func TmuxCmd(args ...string) (string, error) {
-	cmd := exec.Command("tmux", args...)
-	output, err := cmd.CombinedOutput()
-	return strings.TrimSpace(string(output)), err
+	return ExecRunner{}.Run(args...)
+}
+
+// TmuxCmd runs a tmux command on the manager's server and returns stdout
+func (m *Manager) TmuxCmd(args ...string) (string, error) {
+	return m.tmuxCmd(args...)
 }
 
-// tmuxCmd runs a tmux command and returns stdout (internal helper)
-func tmuxCmd(args ...string) (string, error) {
-	return TmuxCmd(args...)
+// tmuxCmd runs a tmux command through the manager's runner and returns stdout
+func (m *Manager) tmuxCmd(args ...string) (string, error) {
+	return m.runner.Run(args...)
 }
 
-// tmuxCmd2 runs a tmux command and only returns error (doesn't capture output)
-func tmuxCmd2(args ...string) error {
-	cmd := exec.Command("tmux", args...)
-	return cmd.Run()
+// tmuxCmd2 runs a tmux command through the manager's runner and only returns error
+func (m *Manager) tmuxCmd2(args ...string) error {
+	_, err := m.runner.Run(args...)
+	return err
 }
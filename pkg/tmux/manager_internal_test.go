@@ -0,0 +1,34 @@
+package tmux
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResourceLoopBacksOff(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+
+	// The command fails at once; its second run stops the loop
+	runs := filepath.Join(t.TempDir(), "runs")
+	command := shellQuote("sh") + " -c " + shellQuote(`echo run >> "$0"
+if [ "$(wc -l < "$0")" -ge 2 ]; then kill $PPID; fi
+exit 3`) + " " + shellQuote(runs)
+	args := getResourceCommand("/bin/sh", "pod-a", ResourceSpec{Command: command})
+	if args[0] != "bash" || args[1] != "-c" {
+		t.Fatalf("command = %q, want bash -c", args)
+	}
+
+	output, _ := exec.Command(args[0], args[1:]...).CombinedOutput()
+
+	if data, _ := os.ReadFile(runs); strings.Count(string(data), "run") != 2 {
+		t.Fatalf("command ran %d times, want 2", strings.Count(string(data), "run"))
+	}
+	if want := "[muxctl] exited with status 3; restarting in 1s"; !strings.Contains(string(output), want) {
+		t.Errorf("output %q does not contain %q", output, want)
+	}
+}
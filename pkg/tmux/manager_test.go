@@ -0,0 +1,762 @@
+package tmux_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/xunzhou/muxctl/pkg/ctl"
+	"github.com/xunzhou/muxctl/pkg/tmux"
+	"github.com/xunzhou/muxctl/pkg/tmux/tmuxtest"
+)
+
+// newTestManager sets up the muxctl layout on a fake tmux server
+func newTestManager(t *testing.T, opts ...tmux.Option) (*tmux.Manager, *tmuxtest.Server) {
+	t.Helper()
+	srv := tmuxtest.NewServer()
+	mgr, err := tmux.NewManager(append([]tmux.Option{tmux.WithRunner(srv)}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if err := mgr.Setup(); err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	return mgr, srv
+}
+
+// mainPanes returns the panes of the TUI's window
+func mainPanes(mgr *tmux.Manager, srv *tmuxtest.Server) []string {
+	return srv.PaneIDs(srv.PaneWindow(mgr.GetTUIPane()))
+}
+
+func TestSetup(t *testing.T) {
+	mgr, srv := newTestManager(t)
+
+	panes := mainPanes(mgr, srv)
+	if len(panes) != 2 || panes[0] != mgr.GetTUIPane() || panes[1] != mgr.GetBottomPane() {
+		t.Fatalf("main window panes = %v, want TUI %s then bottom %s", panes, mgr.GetTUIPane(), mgr.GetBottomPane())
+	}
+	if got := srv.Binding("root", "M-Enter"); len(got) == 0 || got[len(got)-1] != mgr.GetTUIPane() {
+		t.Errorf("M-Enter is bound to %v, want select-pane on the TUI", got)
+	}
+}
+
+func TestAttachResourceTerminal(t *testing.T) {
+	mgr, srv := newTestManager(t)
+	mgr.SetResourceSpec("pod-a", tmux.ResourceSpec{Command: "kubectl exec -it pod-a -- sh"})
+	placeholder := mgr.GetBottomPane()
+
+	if err := mgr.AttachResourceTerminal("pod-a"); err != nil {
+		t.Fatalf("AttachResourceTerminal: %v", err)
+	}
+	pane := mgr.GetResourcePanes()["pod-a"]
+	if pane == "" || mgr.GetBottomPane() != pane {
+		t.Fatalf("bottom pane = %s, want pod-a's pane %s", mgr.GetBottomPane(), pane)
+	}
+	if got := mainPanes(mgr, srv); len(got) != 2 || got[1] != pane {
+		t.Errorf("main window panes = %v, want pod-a's pane below the TUI", got)
+	}
+	if srv.PaneWindow(placeholder) == srv.PaneWindow(mgr.GetTUIPane()) {
+		t.Errorf("the placeholder shell was not swapped out")
+	}
+	if got := mgr.GetActiveResource(); got != "pod-a" {
+		t.Errorf("active resource = %q, want pod-a", got)
+	}
+	if cmd := srv.PaneCommand(pane); !strings.Contains(cmd, "kubectl exec -it pod-a -- sh") {
+		t.Errorf("pane command %q does not run the resource's command", cmd)
+	}
+	if got := srv.PaneOption(pane, "@muxctl-resource"); got != "pod-a" {
+		t.Errorf("@muxctl-resource = %q, want pod-a", got)
+	}
+
+	// Attaching again reuses the pane
+	if err := mgr.AttachResourceTerminal("pod-b"); err != nil {
+		t.Fatalf("AttachResourceTerminal pod-b: %v", err)
+	}
+	if err := mgr.AttachResourceTerminal("pod-a"); err != nil {
+		t.Fatalf("AttachResourceTerminal pod-a again: %v", err)
+	}
+	if got := mgr.GetResourcePanes()["pod-a"]; got != pane {
+		t.Errorf("pod-a's pane changed from %s to %s", pane, got)
+	}
+	podB := mgr.GetResourcePanes()["pod-b"]
+	if podB == "" || srv.PaneWindow(podB) == srv.PaneWindow(mgr.GetTUIPane()) {
+		t.Errorf("pod-b's pane %q was not swapped out", podB)
+	}
+}
+
+func TestAttachAIChat(t *testing.T) {
+	mgr, srv := newTestManager(t)
+	if err := mgr.AttachResourceTerminal("pod-a"); err != nil {
+		t.Fatalf("AttachResourceTerminal: %v", err)
+	}
+
+	if err := mgr.AttachAIChat(); err != nil {
+		t.Fatalf("AttachAIChat: %v", err)
+	}
+	chats := mgr.GetAIChats()
+	if len(chats) != 1 || chats[0].ID != "ai-1" {
+		t.Fatalf("AI chats = %+v, want ai-1", chats)
+	}
+	if mgr.GetBottomPane() != chats[0].PaneID {
+		t.Errorf("bottom pane = %s, want the AI chat's %s", mgr.GetBottomPane(), chats[0].PaneID)
+	}
+	if got := srv.PaneCommand(chats[0].PaneID); got != "claude" {
+		t.Errorf("AI chat runs %q, want claude", got)
+	}
+	if mgr.GetActiveAIChat() != "ai-1" || mgr.GetActiveResource() != "" {
+		t.Errorf("active = %q/%q, want only ai-1", mgr.GetActiveResource(), mgr.GetActiveAIChat())
+	}
+
+	// Back to the resource and to the chat again
+	if err := mgr.AttachResourceTerminal("pod-a"); err != nil {
+		t.Fatalf("AttachResourceTerminal: %v", err)
+	}
+	if err := mgr.AttachExistingAIChat("ai-1"); err != nil {
+		t.Fatalf("AttachExistingAIChat: %v", err)
+	}
+	if got := mainPanes(mgr, srv); len(got) != 2 || got[1] != chats[0].PaneID {
+		t.Errorf("main window panes = %v, want the AI chat below the TUI", got)
+	}
+}
+
+func TestCloseResourcePane(t *testing.T) {
+	mgr, srv := newTestManager(t)
+	for _, id := range []string{"pod-a", "pod-b"} {
+		if err := mgr.AttachResourceTerminal(id); err != nil {
+			t.Fatalf("AttachResourceTerminal %s: %v", id, err)
+		}
+	}
+	panes := mgr.GetResourcePanes()
+
+	// A stashed resource just goes away
+	if err := mgr.CloseResourcePane("pod-a"); err != nil {
+		t.Fatalf("CloseResourcePane pod-a: %v", err)
+	}
+	if srv.PaneWindow(panes["pod-a"]) != "" {
+		t.Errorf("pod-a's pane still exists")
+	}
+
+	// The visible one is replaced by a shell
+	if err := mgr.CloseResourcePane("pod-b"); err != nil {
+		t.Fatalf("CloseResourcePane pod-b: %v", err)
+	}
+	if srv.PaneWindow(panes["pod-b"]) != "" {
+		t.Errorf("pod-b's pane still exists")
+	}
+	got := mainPanes(mgr, srv)
+	if len(got) != 2 || got[1] != mgr.GetBottomPane() {
+		t.Errorf("main window panes = %v, want the TUI and a new bottom pane %s", got, mgr.GetBottomPane())
+	}
+	if len(mgr.GetResourcePanes()) != 0 || mgr.GetActiveResource() != "" {
+		t.Errorf("resources still tracked: %v, active %q", mgr.GetResourcePanes(), mgr.GetActiveResource())
+	}
+	if err := mgr.CloseResourcePane("pod-b"); err == nil {
+		t.Errorf("closing a closed resource succeeded")
+	}
+}
+
+func TestDeadPanesAreForgotten(t *testing.T) {
+	mgr, srv := newTestManager(t)
+	if err := mgr.AttachResourceTerminal("pod-a"); err != nil {
+		t.Fatalf("AttachResourceTerminal: %v", err)
+	}
+	pane := mgr.GetBottomPane()
+
+	// The resource's process exits and tmux closes the pane
+	srv.KillPane(pane)
+	mgr.UpdateStatusBar()
+
+	if _, ok := mgr.GetResourcePanes()["pod-a"]; ok {
+		t.Errorf("pod-a is still tracked")
+	}
+	if got := mainPanes(mgr, srv); len(got) != 2 || got[1] == pane {
+		t.Errorf("main window panes = %v, want the TUI and a new shell", got)
+	}
+}
+
+func TestCapturePaneRange(t *testing.T) {
+	mgr, srv := newTestManager(t)
+	pane := mgr.GetBottomPane()
+	screen, err := mgr.CapturePane(pane, tmux.CaptureOptions{})
+	if err != nil {
+		t.Fatalf("CapturePane: %v", err)
+	}
+
+	// Three lines of scrollback above a full screen
+	var lines []string
+	for i := 0; i < screen.Height+3; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	srv.SetPaneContent(pane, strings.Join(lines, "\n"))
+
+	tests := []struct {
+		name string
+		opts tmux.CaptureOptions
+		want []string
+	}{
+		{"screen", tmux.CaptureOptions{}, lines[3:]},
+		{"history", tmux.CaptureOptions{History: true}, lines},
+		{"history to the first screen line", tmux.CaptureOptions{History: true, End: tmux.Line(0)}, lines[:4]},
+		{"first screen line", tmux.CaptureOptions{Start: tmux.Line(0), End: tmux.Line(0)}, lines[3:4]},
+		{"scrollback", tmux.CaptureOptions{Start: tmux.Line(-2), End: tmux.Line(-1)}, lines[1:3]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capture, err := mgr.CapturePane(pane, tt.opts)
+			if err != nil {
+				t.Fatalf("CapturePane: %v", err)
+			}
+			if want := strings.Join(tt.want, "\n"); capture.Content != want {
+				t.Errorf("content = %q, want %q", capture.Content, want)
+			}
+		})
+	}
+}
+
+func TestCaptureSelection(t *testing.T) {
+	mgr, srv := newTestManager(t, tmux.WithCaptureFilter(func(s string) string {
+		return strings.ReplaceAll(s, "hunter2", "[REDACTED]")
+	}))
+	pane := mgr.GetBottomPane()
+	path := filepath.Join(t.TempDir(), "mine")
+	if err := os.WriteFile(path, []byte("the user's buffer"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	srv.Run("load-buffer", "-b", "mine", path)
+
+	tests := []struct {
+		name      string
+		copyMode  bool
+		selection string
+		want      string
+		ok        bool
+	}{
+		{"not in copy mode", false, "", "", false},
+		{"nothing selected", true, "", "", false},
+		{"selection", true, "password hunter2", "password [REDACTED]", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.copyMode {
+				srv.SetSelection(pane, tt.selection)
+			}
+			text, ok, err := mgr.CaptureSelection(pane)
+			if err != nil {
+				t.Fatalf("CaptureSelection: %v", err)
+			}
+			if text != tt.want || ok != tt.ok {
+				t.Errorf("CaptureSelection = %q, %v, want %q, %v", text, ok, tt.want, tt.ok)
+			}
+			// The copied buffer is dropped and the user's is left alone
+			if got, _ := srv.Run("list-buffers", "-F", "#{buffer_name}"); got != "mine" {
+				t.Errorf("buffers = %q, want only the user's", got)
+			}
+		})
+	}
+}
+
+func TestPasteToPane(t *testing.T) {
+	mgr, srv := newTestManager(t)
+	pane := mgr.GetBottomPane()
+
+	// Text reaches the pane as it is, however tmux or a shell would parse it
+	text := "first line\n\"quoted\" $HOME #{pane_id}; \\ last line\n"
+	if err := mgr.PasteToPane(pane, text); err != nil {
+		t.Fatalf("PasteToPane: %v", err)
+	}
+	if got := srv.SentKeys(pane); !reflect.DeepEqual(got, []string{text}) {
+		t.Errorf("pasted %q, want %q", got, text)
+	}
+	if got, _ := srv.Run("list-buffers", "-F", "#{buffer_name}"); got != "" {
+		t.Errorf("PasteToPane left buffers %q", got)
+	}
+
+	if err := mgr.PasteToPane("%99", text); err == nil {
+		t.Errorf("pasting into a missing pane succeeded")
+	}
+	if got, _ := srv.Run("list-buffers", "-F", "#{buffer_name}"); got != "" {
+		t.Errorf("a failed paste left buffers %q", got)
+	}
+}
+
+func TestDualSlot(t *testing.T) {
+	mgr, srv := newTestManager(t)
+	if err := mgr.AttachResourceTerminal("pod-a"); err != nil {
+		t.Fatalf("AttachResourceTerminal: %v", err)
+	}
+	podA := mgr.GetBottomPane()
+
+	// Splitting keeps the resource in its slot and gives the AI slot a shell
+	if dual, err := mgr.ToggleDualSlot(); err != nil || !dual {
+		t.Fatalf("ToggleDualSlot = %v, %v, want dual-slot", dual, err)
+	}
+	shell := mgr.GetAISlotPane()
+	if got := mainPanes(mgr, srv); !reflect.DeepEqual(got, []string{mgr.GetTUIPane(), podA, shell}) {
+		t.Fatalf("main window panes = %v, want TUI, pod-a %s and a shell", got, podA)
+	}
+
+	// Each kind is swapped into its own slot
+	if err := mgr.AttachAIChat(); err != nil {
+		t.Fatalf("AttachAIChat: %v", err)
+	}
+	chat := mgr.GetActiveAIChat()
+	chatPane := mgr.GetAIPanes()[chat]
+	if err := mgr.AttachResourceTerminal("pod-b"); err != nil {
+		t.Fatalf("AttachResourceTerminal pod-b: %v", err)
+	}
+	podB := mgr.GetResourcePanes()["pod-b"]
+	if got := mainPanes(mgr, srv); !reflect.DeepEqual(got, []string{mgr.GetTUIPane(), podB, chatPane}) {
+		t.Fatalf("main window panes = %v, want TUI, pod-b %s and %s %s", got, podB, chat, chatPane)
+	}
+	if mgr.GetActiveResource() != "pod-b" || mgr.GetActiveAIChat() != chat {
+		t.Errorf("active = %q and %q, want pod-b and %s", mgr.GetActiveResource(), mgr.GetActiveAIChat(), chat)
+	}
+
+	// A muxctl restarted after a crash finds both slots again
+	restarted, err := tmux.NewManager(tmux.WithRunner(srv), tmux.WithTarget(mgr.GetTUIPane()))
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if err := restarted.Setup(); err != nil {
+		t.Fatalf("Setup after restart: %v", err)
+	}
+	if restarted.GetBottomPane() != podB || restarted.GetAISlotPane() != chatPane {
+		t.Errorf("adopted slots = %s and %s, want %s and %s",
+			restarted.GetBottomPane(), restarted.GetAISlotPane(), podB, chatPane)
+	}
+	if restarted.GetActiveResource() != "pod-b" || restarted.GetActiveAIChat() != chat {
+		t.Errorf("adopted active = %q and %q, want pod-b and %s",
+			restarted.GetActiveResource(), restarted.GetActiveAIChat(), chat)
+	}
+
+	// Merging keeps the resource on screen and stashes the AI chat
+	if dual, err := restarted.ToggleDualSlot(); err != nil || dual {
+		t.Fatalf("ToggleDualSlot = %v, %v, want single-slot", dual, err)
+	}
+	if got := mainPanes(restarted, srv); !reflect.DeepEqual(got, []string{restarted.GetTUIPane(), podB}) {
+		t.Errorf("main window panes = %v, want TUI and pod-b %s", got, podB)
+	}
+	if window := srv.PaneWindow(chatPane); window == "" || srv.WindowName(window) != "AI Chat 1" {
+		t.Errorf("%s's pane is in window %q named %q, want a window of its own", chat, window, srv.WindowName(window))
+	}
+	if got := restarted.GetActiveAIChat(); got != "" {
+		t.Errorf("active AI chat = %q after merging, want none", got)
+	}
+}
+
+func TestSwapIntoBottomDualSlot(t *testing.T) {
+	mgr, srv := newTestManager(t)
+	for _, id := range []string{"pod-a", "pod-b"} {
+		if err := mgr.AttachResourceTerminal(id); err != nil {
+			t.Fatalf("AttachResourceTerminal %s: %v", id, err)
+		}
+	}
+	if _, err := mgr.ToggleDualSlot(); err != nil {
+		t.Fatalf("ToggleDualSlot: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := mgr.StartAIChat(tmux.AIChatOptions{}); err != nil {
+			t.Fatalf("StartAIChat: %v", err)
+		}
+	}
+	podA, podB := mgr.GetResourcePanes()["pod-a"], mgr.GetResourcePanes()["pod-b"]
+	ai1, ai2 := mgr.GetAIPanes()["ai-1"], mgr.GetAIPanes()["ai-2"]
+	if got := mainPanes(mgr, srv); !reflect.DeepEqual(got, []string{mgr.GetTUIPane(), podB, ai2}) {
+		t.Fatalf("main window panes = %v, want TUI, pod-b %s and ai-2 %s", got, podB, ai2)
+	}
+
+	// Each pane goes to its kind's slot and leaves the other slot alone
+	for _, step := range []struct {
+		pane           string
+		slots          []string
+		resource, chat string
+	}{
+		{ai1, []string{podB, ai1}, "pod-b", "ai-1"},
+		{podA, []string{podA, ai1}, "pod-a", "ai-1"},
+		{ai2, []string{podA, ai2}, "pod-a", "ai-2"},
+	} {
+		if err := mgr.SwapIntoBottom(step.pane); err != nil {
+			t.Fatalf("SwapIntoBottom %s: %v", step.pane, err)
+		}
+		if got := mainPanes(mgr, srv); !reflect.DeepEqual(got, append([]string{mgr.GetTUIPane()}, step.slots...)) {
+			t.Errorf("after swapping in %s main window panes = %v, want TUI and %v", step.pane, got, step.slots)
+		}
+		if mgr.GetBottomPane() != step.slots[0] || mgr.GetAISlotPane() != step.slots[1] {
+			t.Errorf("after swapping in %s slots = %s and %s, want %v", step.pane, mgr.GetBottomPane(), mgr.GetAISlotPane(), step.slots)
+		}
+		if mgr.GetActiveResource() != step.resource || mgr.GetActiveAIChat() != step.chat {
+			t.Errorf("after swapping in %s active = %q and %q, want %q and %q",
+				step.pane, mgr.GetActiveResource(), mgr.GetActiveAIChat(), step.resource, step.chat)
+		}
+	}
+}
+
+func TestEventsRestart(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	mgr, _ := newTestManager(t)
+
+	if err := mgr.StartEvents(); err != nil {
+		t.Fatalf("StartEvents: %v", err)
+	}
+	first := mgr.Events()
+	mgr.StopEvents()
+	if mgr.Events() != nil {
+		t.Errorf("Events is not nil after StopEvents")
+	}
+	for range first {
+		// Drained once readEvents sees the FIFO closed
+	}
+
+	if err := mgr.StartEvents(); err != nil {
+		t.Fatalf("StartEvents after StopEvents: %v", err)
+	}
+	defer mgr.StopEvents()
+	if mgr.Events() == nil || mgr.Events() == first {
+		t.Errorf("StartEvents did not make a new channel")
+	}
+}
+
+// ctlHandler answers control socket requests straight from a Manager, the
+// way the TUI does from its Bubble Tea goroutine
+type ctlHandler struct {
+	mgr *tmux.Manager
+}
+
+func (h ctlHandler) HandleCtl(req *ctl.Request) *ctl.Response {
+	resp := &ctl.Response{ID: req.ID}
+	var err error
+	switch req.Action {
+	case ctl.ActionList:
+		for id, pane := range h.mgr.GetResourcePanes() {
+			resp.Items = append(resp.Items, ctl.Item{Kind: ctl.KindResource, ID: id, PaneID: pane})
+		}
+		for _, chat := range h.mgr.GetAIChats() {
+			resp.Items = append(resp.Items, ctl.Item{Kind: ctl.KindAI, ID: chat.ID, PaneID: chat.PaneID})
+		}
+	case ctl.ActionAttach:
+		if _, ok := h.mgr.GetAIPanes()[req.ID]; ok {
+			err = h.mgr.AttachExistingAIChat(req.ID)
+		} else {
+			err = h.mgr.AttachResourceTerminal(req.ID)
+		}
+	case ctl.ActionAINew:
+		resp.ID, err = h.mgr.StartAIChat(tmux.AIChatOptions{})
+	}
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	resp.Success = true
+	return resp
+}
+
+// TestConcurrentUse drives the Manager from several goroutines and the
+// control socket at once; run it with -race
+func TestConcurrentUse(t *testing.T) {
+	mgr, srv := newTestManager(t)
+
+	path := filepath.Join(t.TempDir(), "ctl.sock")
+	server, err := ctl.Listen(path, ctlHandler{mgr})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	go server.Serve()
+	defer server.Close()
+
+	const rounds = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	spawn := func(name string, f func(i int) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				if err := f(i); err != nil {
+					errs <- fmt.Errorf("%s: %w", name, err)
+					return
+				}
+			}
+		}()
+	}
+
+	for n := 0; n < 3; n++ {
+		n := n
+		spawn("attach", func(i int) error {
+			return mgr.AttachResourceTerminal(fmt.Sprintf("pod-%d", (n+i)%4))
+		})
+	}
+	spawn("ai new", func(i int) error {
+		if i%5 != 0 {
+			return nil
+		}
+		if i%10 == 0 {
+			return mgr.AttachAIChat()
+		}
+		_, err := mgr.StartAIChat(tmux.AIChatOptions{})
+		return err
+	})
+	spawn("ai attach", func(i int) error {
+		for _, chat := range mgr.GetAIChats() {
+			if err := mgr.AttachExistingAIChat(chat.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	spawn("readers", func(i int) error {
+		mgr.GetResourcePanes()
+		mgr.GetAIPanes()
+		mgr.GetPaneInfo()
+		mgr.GetActiveResource()
+		mgr.UpdateStatusBar()
+		return nil
+	})
+	spawn("socket", func(i int) error {
+		reqs := []*ctl.Request{
+			{Action: ctl.ActionList},
+			{Action: ctl.ActionAttach, ID: fmt.Sprintf("pod-%d", i%4)},
+		}
+		if i == 0 {
+			reqs = append(reqs, &ctl.Request{Action: ctl.ActionAINew})
+		}
+		for _, req := range reqs {
+			if _, err := ctl.Do(path, req); err != nil {
+				return fmt.Errorf("%s: %w", req.Action, err)
+			}
+		}
+		return nil
+	})
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// Whatever ran last, the layout is intact and every pane is tracked
+	if got := mainPanes(mgr, srv); len(got) != 2 || got[1] != mgr.GetBottomPane() {
+		t.Errorf("main window panes = %v, want the TUI and bottom pane %s", got, mgr.GetBottomPane())
+	}
+	if got := len(mgr.GetResourcePanes()); got != 4 {
+		t.Errorf("%d resource panes, want 4", got)
+	}
+	for id, pane := range mgr.GetResourcePanes() {
+		if srv.PaneWindow(pane) == "" {
+			t.Errorf("%s's pane %s does not exist", id, pane)
+		}
+	}
+	if got := len(mgr.GetAIChats()); got != 5 {
+		t.Errorf("%d AI chats, want 5", got)
+	}
+}
+
+func TestWindowsAreNamedAfterTheirPane(t *testing.T) {
+	mgr, srv := newTestManager(t)
+	if err := mgr.AttachAIChat(); err != nil {
+		t.Fatalf("AttachAIChat: %v", err)
+	}
+	chat := mgr.GetAIChats()[0]
+	if err := mgr.RenameAIChat(chat.ID, "oom triage"); err != nil {
+		t.Fatalf("RenameAIChat: %v", err)
+	}
+
+	// The chat is swapped out into the window pod-a was created in
+	if err := mgr.AttachResourceTerminal("pod-a"); err != nil {
+		t.Fatalf("AttachResourceTerminal: %v", err)
+	}
+	if got := srv.WindowName(srv.PaneWindow(chat.PaneID)); got != "ai: oom triage" {
+		t.Errorf("AI chat's window = %q, want its title", got)
+	}
+
+	// And back, taking pod-a's name with it
+	if err := mgr.AttachExistingAIChat(chat.ID); err != nil {
+		t.Fatalf("AttachExistingAIChat: %v", err)
+	}
+	pod := mgr.GetResourcePanes()["pod-a"]
+	if got := srv.WindowName(srv.PaneWindow(pod)); got != "Resource: pod-a" {
+		t.Errorf("pod-a's window = %q, want Resource: pod-a", got)
+	}
+
+	// Stashed by the dual-slot layout
+	if _, err := mgr.ToggleDualSlot(); err != nil {
+		t.Fatalf("ToggleDualSlot: %v", err)
+	}
+	if _, err := mgr.ToggleDualSlot(); err != nil {
+		t.Fatalf("ToggleDualSlot: %v", err)
+	}
+	for pane, want := range map[string]string{chat.PaneID: "ai: oom triage", pod: "Resource: pod-a"} {
+		win := srv.PaneWindow(pane)
+		if win == srv.PaneWindow(mgr.GetTUIPane()) {
+			continue
+		}
+		if got := srv.WindowName(win); got != want {
+			t.Errorf("window of %s = %q, want %q", pane, got, want)
+		}
+	}
+}
+
+func TestAIChatsInTabOrder(t *testing.T) {
+	mgr, srv := newTestManager(t)
+	mgr.SetAIBackends([]tmux.AIBackend{
+		{Name: "claude", Command: []string{"claude"}, Prefix: "ai"},
+		{Name: "local", Command: []string{"ollama", "run", "llama3"}, Prefix: "llm"},
+	})
+	for _, backend := range []string{"local", "claude", "local"} {
+		if _, err := mgr.StartAIChat(tmux.AIChatOptions{Backend: backend}); err != nil {
+			t.Fatalf("StartAIChat %s: %v", backend, err)
+		}
+	}
+	if err := mgr.RenameAIChat("ai-3", "oom"); err != nil {
+		t.Fatalf("RenameAIChat: %v", err)
+	}
+
+	// Grouped by backend like the tabs, so the Nth chat has tab N
+	var ids []string
+	for _, chat := range mgr.GetAIChats() {
+		ids = append(ids, chat.ID)
+	}
+	if want := []string{"ai-2", "ai-1", "ai-3"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("GetAIChats = %v, want %v", ids, want)
+	}
+
+	// Without styles and ranges the tabs read "ai 1 llm 2 3:oom"
+	status := regexp.MustCompile(`#\[[^]]*\]`).ReplaceAllString(srv.Option("status-right"), "")
+	if got, want := strings.Fields(status), []string{"ai", "1", "llm", "2", "3:oom"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("status-right tabs = %q, want %q", got, want)
+	}
+}
+
+func TestResourceTabsEscapeFormats(t *testing.T) {
+	mgr, srv := newTestManager(t)
+	mgr.SetResourceSpec("db#{pane_id}", tmux.ResourceSpec{Command: "cat"})
+	if err := mgr.AttachResourceTerminal("db#{pane_id}"); err != nil {
+		t.Fatalf("AttachResourceTerminal: %v", err)
+	}
+
+	status := regexp.MustCompile(`#\[[^]]*\]`).ReplaceAllString(srv.Option("status-left"), "")
+	if got, want := strings.Fields(status), []string{"•", "db##{pane_id}"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("status-left tabs = %q, want %q", got, want)
+	}
+}
+
+func TestCleanupRestoresStatusClick(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []string // bind-key arguments run before Setup (nil = unbound)
+		during []string // ... and while muxctl runs
+		click  string   // Command run by clicks outside the tabs ("" = none)
+		want   []string // MouseDown1Status binding after Cleanup
+	}{
+		{
+			name: "unbound",
+		},
+		{
+			name:   "user binding",
+			before: []string{"if-shell", "-F", "#{==:#{mouse_status_range},window}", "select-window -t =", "display-message 'status: #{mouse_status_range}'"},
+			click:  `if-shell -F "#{==:#{mouse_status_range},window}" "select-window -t =" "display-message 'status: #{mouse_status_range}'"`,
+			want:   []string{"if-shell", "-F", "#{==:#{mouse_status_range},window}", "select-window -t =", "display-message 'status: #{mouse_status_range}'"},
+		},
+		{
+			name:   "rebound while running",
+			before: []string{"select-window", "-t", "="},
+			during: []string{"choose-tree"},
+			click:  "select-window -t =",
+			want:   []string{"choose-tree"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := tmuxtest.NewServer()
+			if tt.before != nil {
+				if _, err := srv.Run(append([]string{"bind-key", "-n", "MouseDown1Status"}, tt.before...)...); err != nil {
+					t.Fatal(err)
+				}
+			}
+			mgr, err := tmux.NewManager(tmux.WithRunner(srv), tmux.WithTabClickCommand("muxctl click #{mouse_status_range}"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := mgr.Setup(); err != nil {
+				t.Fatalf("Setup: %v", err)
+			}
+			got := srv.Binding("root", "MouseDown1Status")
+			if len(got) < 4 || got[0] != "if-shell" {
+				t.Fatalf("Setup bound MouseDown1Status to %q", got)
+			}
+			if click := strings.Join(got[4:], ""); click != tt.click {
+				t.Errorf("clicks outside the tabs run %q, want %q", click, tt.click)
+			}
+			if tt.during != nil {
+				srv.Run(append([]string{"bind-key", "-n", "MouseDown1Status"}, tt.during...)...)
+			}
+
+			mgr.Cleanup()
+
+			if got := srv.Binding("root", "MouseDown1Status"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("after Cleanup MouseDown1Status = %q, want %q", got, tt.want)
+			}
+			if got := srv.Option("@muxctl-saved-key-MouseDown1Status"); got != "" {
+				t.Errorf("saved binding left behind: %q", got)
+			}
+		})
+	}
+}
+
+func TestRestartAfterCrashKeepsUserBinding(t *testing.T) {
+	srv := tmuxtest.NewServer()
+	srv.Run("bind-key", "-n", "MouseDown1Status", "choose-tree")
+	for i := 0; i < 2; i++ {
+		// The first instance never runs Cleanup
+		mgr, err := tmux.NewManager(tmux.WithRunner(srv), tmux.WithTabClickCommand("muxctl click"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := mgr.Setup(); err != nil {
+			t.Fatalf("Setup: %v", err)
+		}
+		// The restarted instance falls back to the user's binding, not to
+		// its predecessor's
+		if got := srv.Binding("root", "MouseDown1Status"); len(got) != 5 || got[4] != "choose-tree" {
+			t.Errorf("instance %d bound MouseDown1Status to %q, want choose-tree outside the tabs", i, got)
+		}
+		if i == 1 {
+			mgr.Cleanup()
+		}
+	}
+	if got := srv.Binding("root", "MouseDown1Status"); !reflect.DeepEqual(got, []string{"choose-tree"}) {
+		t.Errorf("MouseDown1Status = %q, want the user's choose-tree", got)
+	}
+}
+
+func TestCleanupRestoresAltEnter(t *testing.T) {
+	srv := tmuxtest.NewServer()
+	srv.Run("bind-key", "-n", "M-Enter", "resize-pane", "-Z")
+	mgr, err := tmux.NewManager(tmux.WithRunner(srv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.Setup(); err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	if got := srv.Binding("root", "M-Enter"); !reflect.DeepEqual(got, []string{"select-pane", "-t", mgr.GetTUIPane()}) {
+		t.Fatalf("Setup bound M-Enter to %q", got)
+	}
+
+	mgr.Cleanup()
+	if got := srv.Binding("root", "M-Enter"); !reflect.DeepEqual(got, []string{"resize-pane", "-Z"}) {
+		t.Errorf("after Cleanup M-Enter = %q, want the user's resize-pane -Z", got)
+	}
+
+	// Without a binding of their own, the key is left unbound
+	mgr, srv = newTestManager(t)
+	mgr.Cleanup()
+	if got := srv.Binding("root", "M-Enter"); got != nil {
+		t.Errorf("after Cleanup M-Enter = %q, want unbound", got)
+	}
+}
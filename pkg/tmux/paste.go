@@ -0,0 +1,39 @@
+package tmux
+
+import (
+	"fmt"
+	"os"
+)
+
+// contextBuffer is the paste buffer PasteToPane loads text into
+const contextBuffer = "muxctl-paste"
+
+// PasteToPane pastes text into a pane through a paste buffer
+// Unlike send-keys, the text arrives as one bracketed paste when the
+// program asks for it, so newlines do not submit a chat line by line
+func (m *Manager) PasteToPane(paneID, text string) error {
+	// load-buffer reads a file so the text never passes through the tmux
+	// command parser
+	f, err := os.CreateTemp("", "muxctl-paste-*")
+	if err != nil {
+		return fmt.Errorf("create paste file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return fmt.Errorf("write paste file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write paste file: %w", err)
+	}
+
+	if err := m.tmuxCmd2("load-buffer", "-b", contextBuffer, f.Name()); err != nil {
+		return fmt.Errorf("load paste buffer: %w", err)
+	}
+	// -d deletes the buffer afterwards, -p uses bracketed paste
+	if err := m.tmuxCmd2("paste-buffer", "-d", "-p", "-b", contextBuffer, "-t", paneID); err != nil {
+		m.tmuxCmd("delete-buffer", "-b", contextBuffer)
+		return fmt.Errorf("paste buffer: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package tmux
+
+import (
+	"fmt"
+	"strings"
+)
+
+// optRecording holds the log file a resource pane's output is piped into
+const optRecording = "@muxctl-recording"
+
+// WithRecordCommand makes StartRecording pipe pane output into command with
+// pipe-pane; the log path, resource ID, pane width and pane height are
+// appended as arguments, e.g. "muxctl record-pipe"
+func WithRecordCommand(command string) Option {
+	return func(m *Manager) {
+		m.recordCommand = command
+	}
+}
+
+// StartRecording pipes the output of a resource's pane into a log file
+// The pane must be open; recording stops when the pane is closed
+func (m *Manager) StartRecording(resourceID, path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.recordCommand == "" {
+		return fmt.Errorf("recording is not configured")
+	}
+	paneID, ok := m.resourcePanes[resourceID]
+	if !ok {
+		return fmt.Errorf("resource %s has no pane", resourceID)
+	}
+
+	size, err := m.tmuxCmd("display-message", "-p", "-t", paneID, "#{pane_width} #{pane_height}")
+	if err != nil {
+		return fmt.Errorf("get pane size: %w", err)
+	}
+	width, height, _ := strings.Cut(size, " ")
+
+	// pipe-pane expands formats in its command, so "#" is doubled
+	args := []string{path, resourceID, width, height}
+	for i, arg := range args {
+		args[i] = strings.ReplaceAll(shellQuote(arg), "#", "##")
+	}
+	command := m.recordCommand + " " + strings.Join(args, " ")
+	if err := m.tmuxCmd2("pipe-pane", "-t", paneID, command); err != nil {
+		return fmt.Errorf("start recording: %w", err)
+	}
+
+	m.tmuxCmd("set-option", "-p", "-t", paneID, optRecording, path)
+	m.recordings[resourceID] = path
+	m.updateStatusBar()
+	return nil
+}
+
+// StopRecording closes the pipe from a resource's pane and returns the log
+// file it was written to
+func (m *Manager) StopRecording(resourceID string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path, ok := m.recordings[resourceID]
+	if !ok {
+		return "", fmt.Errorf("%s is not being recorded", resourceID)
+	}
+	delete(m.recordings, resourceID)
+
+	if paneID, ok := m.resourcePanes[resourceID]; ok {
+		// pipe-pane without a command closes the pipe
+		if err := m.tmuxCmd2("pipe-pane", "-t", paneID); err != nil {
+			return path, fmt.Errorf("stop recording: %w", err)
+		}
+		m.tmuxCmd("set-option", "-p", "-u", "-t", paneID, optRecording)
+	}
+	m.updateStatusBar()
+	return path, nil
+}
+
+// GetRecording returns the log file a resource is being recorded to, or ""
+func (m *Manager) GetRecording(resourceID string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.recordings[resourceID]
+}
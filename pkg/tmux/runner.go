@@ -0,0 +1,103 @@
+package tmux
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// Runner executes tmux commands on behalf of a Manager
+type Runner interface {
+	// Run runs a tmux command and returns its output without the final
+	// newline; other whitespace, such as the blank lines at the bottom of a
+	// captured pane, is kept
+	Run(args ...string) (string, error)
+}
+
+// ExecRunner runs each tmux command as a separate tmux process
+type ExecRunner struct {
+	Args []string // Passed to tmux before every command, e.g. "-L", "name"
+}
+
+// Run execs tmux with the given arguments and returns its combined output
+func (r ExecRunner) Run(args ...string) (string, error) {
+	cmd := exec.Command("tmux", append(append([]string(nil), r.Args...), args...)...)
+	output, err := cmd.CombinedOutput()
+	return strings.TrimSuffix(string(output), "\n"), err
+}
+
+// Option configures a Manager
+type Option func(*Manager)
+
+// WithRunner makes the Manager send all tmux commands through r
+func WithRunner(r Runner) Option {
+	return func(m *Manager) {
+		m.runner = r
+	}
+}
+
+// WithSocketName makes the Manager talk to the tmux server with the given
+// socket name (tmux -L) instead of the one in $TMUX
+// It applies to the default runner; pass the same arguments to
+// NewControlRunner when using control mode
+// -u is passed as well: outside tmux the client would otherwise replace tabs
+// and non-ASCII characters in command output with "_"
+func WithSocketName(name string) Option {
+	return func(m *Manager) {
+		m.serverArgs = []string{"-u", "-L", name}
+	}
+}
+
+// WithSocketPath makes the Manager talk to the tmux server listening on the
+// socket at path (tmux -S) instead of the one in $TMUX
+// It applies to the default runner like WithSocketName
+func WithSocketPath(path string) Option {
+	return func(m *Manager) {
+		m.serverArgs = []string{"-u", "-S", path}
+	}
+}
+
+// NewRunner returns the runner a Manager created with opts would use, for
+// running tmux commands before a Manager exists, e.g. to create a session on
+// a dedicated server
+func NewRunner(opts ...Option) Runner {
+	m := &Manager{runner: ExecRunner{}}
+	m.applyOptions(opts)
+	return m.runner
+}
+
+// ServerArgs returns the arguments a Manager created with opts passes to tmux
+// before every command, e.g. "-u", "-L", "name", for starting a
+// ControlRunner on the same server
+func ServerArgs(opts ...Option) []string {
+	m := &Manager{}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m.serverArgs
+}
+
+// WithTabClickCommand makes clicks on status bar tabs run command with
+// run-shell; "#{mouse_status_range}" in command expands to the clicked tab's
+// pane number (the pane ID without "%"), e.g. "muxctl click #{mouse_status_range}"
+func WithTabClickCommand(command string) Option {
+	return func(m *Manager) {
+		m.tabClickCommand = command
+	}
+}
+
+// WithExitMode chooses what Cleanup does with muxctl's panes; the default is
+// ExitTeardown
+func WithExitMode(mode ExitMode) Option {
+	return func(m *Manager) {
+		m.exitMode = mode
+	}
+}
+
+// WithTarget sets the pane the TUI runs in, used to locate the main window
+// This is required for runners that have no notion of the calling pane, such
+// as control mode, where commands run in the control client's context
+func WithTarget(target string) Option {
+	return func(m *Manager) {
+		m.target = target
+	}
+}
@@ -0,0 +1,203 @@
+package tmux
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// In the single-slot layout one pane below the TUI shows either a resource or
+// an AI chat. In the dual-slot layout that pane is split into a resource slot
+// (bottomPane, left or top) and an AI slot (aiSlotPane, right or bottom) that
+// are swapped independently, so a shell and the AI chat about it can be seen
+// together
+
+// IsDualSlot reports whether resources and AI chats have separate slots
+func (m *Manager) IsDualSlot() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.aiSlotPane != ""
+}
+
+// ToggleDualSlot switches between the single-slot and dual-slot layouts and
+// reports whether the dual-slot layout is now shown
+func (m *Manager) ToggleDualSlot() (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var err error
+	if m.aiSlotPane == "" {
+		err = m.splitSlots()
+	} else {
+		err = m.mergeSlots()
+	}
+	m.updateStatusBar()
+	return m.aiSlotPane != "", err
+}
+
+// GetAISlotPane returns the AI slot pane ID ("" in the single-slot layout)
+func (m *Manager) GetAISlotPane() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.aiSlotPane
+}
+
+// splitSlots splits the bottom pane into a resource slot and an AI slot
+// The pane on screen keeps its kind's slot and the other slot gets a shell
+func (m *Manager) splitSlots() error {
+	if m.activeAIChat != "" {
+		// An AI chat is shown: put a shell in the resource slot before it
+		pane, err := m.tmuxCmd("split-window", m.slotSplit(), "-b", "-t", m.bottomPane, "-P", "-F", "#{pane_id}", getWrapperCommand(m.userShell))
+		if err != nil {
+			return fmt.Errorf("create resource slot: %w", err)
+		}
+		m.tagPane(pane, kindDefault, "")
+		m.aiSlotPane = m.bottomPane
+		m.bottomPane = pane
+	} else {
+		pane, err := m.tmuxCmd("split-window", m.slotSplit(), "-t", m.bottomPane, "-P", "-F", "#{pane_id}", getWrapperCommand(m.userShell))
+		if err != nil {
+			return fmt.Errorf("create AI slot: %w", err)
+		}
+		m.tagPane(pane, kindDefault, "")
+		m.aiSlotPane = pane
+	}
+	// Splitting the bottom pane leaves the TUI's size alone
+	if !m.layout.PreserveResize {
+		m.applyLayout()
+	}
+	return nil
+}
+
+// mergeSlots returns to a single slot, keeping the AI chat on screen when no
+// resource is shown and the resource otherwise
+// The hidden slot's pane is broken out into its own window like any stashed
+// resource or AI chat, or killed if it is only a placeholder shell
+func (m *Manager) mergeSlots() error {
+	keepAI := m.activeResource == "" && m.activeAIChat != ""
+
+	hidden := m.aiSlotPane
+	if keepAI {
+		hidden = m.bottomPane
+	}
+	if err := m.stashSlotPane(hidden); err != nil {
+		return err
+	}
+
+	if keepAI {
+		m.bottomPane = m.aiSlotPane
+		m.activeResource = ""
+	} else {
+		m.activeAIChat = ""
+	}
+	m.aiSlotPane = ""
+	if !m.layout.PreserveResize {
+		m.applyLayout()
+	}
+	return nil
+}
+
+// stashSlotPane moves a pane out of the main window into a hidden window of
+// its own, or kills it if it belongs to no resource or AI chat
+func (m *Manager) stashSlotPane(paneID string) error {
+	name := m.windowName(paneID)
+	if name == "" {
+		if err := m.tmuxCmd2("kill-pane", "-t", paneID); err != nil {
+			return fmt.Errorf("kill slot pane: %w", err)
+		}
+		return nil
+	}
+
+	winID, err := m.tmuxCmd("break-pane", "-d", "-s", paneID, "-n", name, "-P", "-F", "#{window_id}")
+	if err != nil {
+		return fmt.Errorf("stash slot pane: %w", err)
+	}
+	m.tmuxCmd("set-window-option", "-t", winID, "window-status-format", "")
+	m.tmuxCmd("set-window-option", "-t", winID, "window-status-current-format", "")
+	return nil
+}
+
+// refillSlot puts a new shell in the slot whose pane was killed and returns
+// the new pane
+func (m *Manager) refillSlot(deadPane, command string) (string, error) {
+	var args []string
+	switch {
+	case m.aiSlotPane != "" && deadPane == m.aiSlotPane:
+		args = []string{"split-window", m.slotSplit(), "-t", m.bottomPane}
+	case m.aiSlotPane != "":
+		args = []string{"split-window", m.slotSplit(), "-b", "-t", m.aiSlotPane}
+	default:
+		args = []string{"split-window", m.mainSplit(), "-t", m.tuiPane}
+	}
+	pane, err := m.tmuxCmd(append(args, "-P", "-F", "#{pane_id}", command)...)
+	if err != nil {
+		return "", fmt.Errorf("create replacement pane: %w", err)
+	}
+
+	if m.aiSlotPane != "" && deadPane == m.aiSlotPane {
+		m.aiSlotPane = pane
+	} else {
+		m.bottomPane = pane
+	}
+	m.tagPane(pane, kindDefault, "")
+
+	// A dead slot's space went to the other slot, leaving the TUI as it was;
+	// the only slot's went to the TUI, so it is sized again regardless
+	if !m.layout.PreserveResize || m.aiSlotPane == "" {
+		m.applyLayout()
+	}
+	return pane, nil
+}
+
+// slotFor returns the slot an AI chat or resource pane is swapped into
+func (m *Manager) slotFor(ai bool) *string {
+	if ai && m.aiSlotPane != "" {
+		return &m.aiSlotPane
+	}
+	return &m.bottomPane
+}
+
+// adoptSlots restores the dual-slot layout from the two panes beside the
+// TUI, the left or top one being the resource slot
+func (m *Manager) adoptSlots() error {
+	output, err := m.tmuxCmd("list-panes", "-t", m.mainWindow, "-F", "#{pane_left}\t#{pane_top}\t#{pane_id}")
+	if err != nil {
+		return fmt.Errorf("list main window panes: %w", err)
+	}
+
+	type slotPane struct {
+		left, top int
+		id        string
+	}
+	var panes []slotPane
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 || fields[2] == m.tuiPane {
+			continue
+		}
+		left, _ := strconv.Atoi(fields[0])
+		top, _ := strconv.Atoi(fields[1])
+		panes = append(panes, slotPane{left, top, fields[2]})
+	}
+	if len(panes) != 2 {
+		return fmt.Errorf("unexpected pane count: %d (expected 1, 2 or 3)", len(panes)+1)
+	}
+	sort.Slice(panes, func(i, j int) bool {
+		if panes[i].left != panes[j].left {
+			return panes[i].left < panes[j].left
+		}
+		return panes[i].top < panes[j].top
+	})
+
+	m.bottomPane, m.aiSlotPane = panes[0].id, panes[1].id
+	m.adoptBottomPane()
+	for aiID, paneID := range m.aiPanes {
+		if paneID == m.aiSlotPane {
+			m.activeAIChat = aiID
+		}
+	}
+	return nil
+}
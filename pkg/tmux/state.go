@@ -0,0 +1,136 @@
+package tmux
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pane user options that record muxctl state in tmux itself, so a restarted
+// muxctl can re-adopt the panes it created
+// Options are set per pane (not per window) because swap-pane moves panes
+// between windows while window options stay behind
+const (
+	optKind     = "@muxctl-kind"     // What the pane is, one of the pane kinds below
+	optResource = "@muxctl-resource" // Resource or AI chat ID owning the pane
+	optBackend  = "@muxctl-backend"  // AI backend an AI chat pane runs
+	optSession  = "@muxctl-session"  // Backend session ID of an AI chat pane
+	optTitle    = "@muxctl-title"    // User-chosen title of an AI chat pane
+)
+
+// Pane kinds stored in @muxctl-kind
+const (
+	kindResource = "resource"
+	kindAI       = "ai"
+	kindStash    = "stash"
+	kindAIStash  = "ai-stash"
+	kindDefault  = "default"
+)
+
+// tagPane records what a pane is so it can be re-adopted after a restart
+func (m *Manager) tagPane(paneID, kind, id string) {
+	m.tmuxCmd("set-option", "-p", "-t", paneID, optKind, kind)
+	if id != "" {
+		m.tmuxCmd("set-option", "-p", "-t", paneID, optResource, id)
+	}
+}
+
+// tagAIChat records which backend and session an AI chat pane runs
+func (m *Manager) tagAIChat(paneID, backend, session string) {
+	m.tmuxCmd("set-option", "-p", "-t", paneID, optBackend, backend)
+	if session != "" {
+		m.tmuxCmd("set-option", "-p", "-t", paneID, optSession, session)
+	}
+}
+
+// adoptPanes rebuilds manager state from panes tagged by a previous muxctl
+// instance in this session
+func (m *Manager) adoptPanes() error {
+	format := strings.Join([]string{
+		"#{pane_id}", "#{window_id}",
+		"#{" + optKind + "}", "#{" + optResource + "}",
+		"#{" + optBackend + "}", "#{" + optSession + "}",
+		"#{" + optTitle + "}", "#{" + optRecording + "}", "#{pane_pipe}",
+	}, "\t")
+	output, err := m.tmuxCmd("list-panes", "-s", "-t", m.mainWindow, "-F", format)
+	if err != nil {
+		return fmt.Errorf("list session panes: %w", err)
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 9 || fields[0] == m.tuiPane {
+			continue
+		}
+		paneID, windowID, kind, id := fields[0], fields[1], fields[2], fields[3]
+
+		switch kind {
+		case kindResource:
+			if id != "" {
+				m.resourcePanes[id] = paneID
+				// The pipe outlives muxctl, so a recording keeps going
+				if fields[7] != "" && fields[8] == "1" {
+					m.recordings[id] = fields[7]
+				}
+			}
+		case kindAI:
+			if id != "" {
+				m.aiPanes[id] = paneID
+				// Chats from before backends were configurable ran claude
+				m.aiChatBackends[id] = fields[4]
+				if fields[4] == "" {
+					m.aiChatBackends[id] = defaultAIBackend.Name
+				}
+				m.aiSessions[id] = fields[5]
+				if fields[6] != "" {
+					m.aiTitles[id] = fields[6]
+				}
+				var aiNum int
+				if _, err := fmt.Sscanf(id, "ai-%d", &aiNum); err == nil && aiNum > m.aiCounter {
+					m.aiCounter = aiNum
+				}
+			}
+		case kindStash:
+			if m.stashWindow == "" {
+				m.stashWindow = windowID
+			}
+		case kindAIStash:
+			if m.aiStashWindow == "" {
+				m.aiStashWindow = windowID
+			}
+		}
+	}
+	return nil
+}
+
+// killOwnedPanes kills every pane muxctl tagged in this session except the
+// TUI; windows left without panes close with them
+func (m *Manager) killOwnedPanes() {
+	output, err := m.tmuxCmd("list-panes", "-s", "-t", m.mainWindow, "-F", "#{pane_id}\t#{"+optKind+"}")
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 2 || fields[1] == "" || fields[0] == m.tuiPane {
+			continue
+		}
+		m.tmuxCmd("kill-pane", "-t", fields[0])
+	}
+}
+
+// adoptBottomPane restores the active resource or AI chat from the pane that
+// is currently shown below the TUI
+func (m *Manager) adoptBottomPane() {
+	for resID, paneID := range m.resourcePanes {
+		if paneID == m.bottomPane {
+			m.activeResource = resID
+			return
+		}
+	}
+	for aiID, paneID := range m.aiPanes {
+		if paneID == m.bottomPane {
+			m.activeAIChat = aiID
+			return
+		}
+	}
+}
@@ -0,0 +1,5 @@
+#!/bin/sh
+# Stands in for an AI CLI: prints each argument on its own line
+for arg in "$@"; do
+	printf '%s\n' "$arg"
+done
@@ -0,0 +1,146 @@
+package tmux
+
+import "fmt"
+
+// Theme styles the status bar, pane borders and status bar tabs
+// Values are tmux styles such as "bg=colour39,fg=black"
+type Theme struct {
+	StatusStyle       string // status-style
+	BorderStyle       string // pane-border-style
+	ActiveBorderStyle string // pane-active-border-style
+	ActiveTab         string // Tab shown in the bottom pane
+	InactiveTab       string // Other tabs of the kind shown in the bottom pane ("" = unstyled)
+	DimTab            string // Tabs of the other kind, e.g. resources while an AI chat is shown
+	RecordingTab      string // Marker in front of resources being recorded
+}
+
+// DarkTheme suits dark terminal backgrounds and is used by default
+var DarkTheme = Theme{
+	StatusStyle:       "bg=colour39,fg=black",
+	BorderStyle:       "fg=colour240",
+	ActiveBorderStyle: "fg=colour39",
+	ActiveTab:         "reverse",
+	DimTab:            "dim",
+	RecordingTab:      "fg=colour196",
+}
+
+// LightTheme suits light terminal backgrounds
+var LightTheme = Theme{
+	StatusStyle:       "bg=colour252,fg=colour235",
+	BorderStyle:       "fg=colour250",
+	ActiveBorderStyle: "fg=colour33",
+	ActiveTab:         "bg=colour33,fg=colour255,bold",
+	DimTab:            "fg=colour245",
+	RecordingTab:      "fg=colour160",
+}
+
+// ThemePreset returns the built-in theme called name ("dark" or "light")
+func ThemePreset(name string) (Theme, bool) {
+	switch name {
+	case "dark":
+		return DarkTheme, true
+	case "light":
+		return LightTheme, true
+	}
+	return Theme{}, false
+}
+
+// WithTheme styles the status bar and pane borders with theme instead of
+// DarkTheme
+func WithTheme(theme Theme) Option {
+	return func(m *Manager) {
+		m.theme = theme
+	}
+}
+
+// styleTab renders text in a tmux style and resets the style afterwards
+// #[default] only resets colours and attributes, so an enclosing range is kept
+func styleTab(style, text string) string {
+	if style == "" {
+		return text
+	}
+	return fmt.Sprintf("#[%s]%s#[default]", style, text)
+}
+
+// applyTheme sets the options the theme controls
+func (m *Manager) applyTheme() {
+	m.tmuxCmd("set-option", "-g", "status-style", m.theme.StatusStyle)
+	m.tmuxCmd("set-option", "-g", "pane-border-style", m.theme.BorderStyle)
+	m.tmuxCmd("set-option", "-g", "pane-active-border-style", m.theme.ActiveBorderStyle)
+}
+
+// savedOptions are the global options Setup changes; their values are saved
+// beforehand so Cleanup can put back exactly what the user had
+var savedOptions = []string{
+	"status-style",
+	"status-left",
+	"status-left-length",
+	"status-right",
+	"status-right-length",
+	"window-status-format",
+	"window-status-current-format",
+	"pane-border-style",
+	"pane-active-border-style",
+}
+
+// Global user options holding the saved values, so a muxctl restarted after
+// a crash restores the user's options rather than its predecessor's
+const (
+	optSaved       = "@muxctl-saved"  // Set once the values below are saved
+	optSavedPrefix = "@muxctl-saved-" // Followed by the option name
+)
+
+// saveOptions records the current values of savedOptions, or loads the
+// values a previous muxctl instance saved
+func (m *Manager) saveOptions() error {
+	m.originalOptions = make(map[string]string, len(savedOptions))
+
+	saved, err := m.showOption(optSaved)
+	if err != nil {
+		return err
+	}
+	for _, name := range savedOptions {
+		key := name
+		if saved == "1" {
+			key = optSavedPrefix + name
+		}
+		value, err := m.showOption(key)
+		if err != nil {
+			return err
+		}
+		m.originalOptions[name] = value
+		if saved != "1" {
+			m.tmuxCmd("set-option", "-g", optSavedPrefix+name, value)
+		}
+	}
+	m.tmuxCmd("set-option", "-g", optSaved, "1")
+	return nil
+}
+
+// showOption returns a global option's value, "" if it is unset
+// It reads the global value that restoreOptions sets rather than one a
+// session may override; runners drop only the newline show-options adds, so
+// trailing whitespace, as status-left usually has, comes back intact
+func (m *Manager) showOption(name string) (string, error) {
+	output, err := m.tmuxCmd("show-options", "-gqv", name)
+	if err != nil {
+		return "", fmt.Errorf("show option %s: %w", name, err)
+	}
+	return output, nil
+}
+
+// restoreOptions puts back the values saveOptions recorded and forgets them
+func (m *Manager) restoreOptions() {
+	if m.originalOptions == nil {
+		return
+	}
+	for _, name := range savedOptions {
+		value, ok := m.originalOptions[name]
+		if !ok {
+			continue
+		}
+		m.tmuxCmd("set-option", "-g", name, value)
+		m.tmuxCmd("set-option", "-gu", optSavedPrefix+name)
+	}
+	m.tmuxCmd("set-option", "-gu", optSaved)
+}
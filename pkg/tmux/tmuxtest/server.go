@@ -0,0 +1,1563 @@
+// Package tmuxtest provides an in-memory fake tmux server for testing code
+// built on tmux.Manager without a live tmux
+package tmuxtest
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Server is a fake tmux server that implements tmux.Runner
+// It models sessions, windows, panes and options closely enough for the
+// commands issued by tmux.Manager; commands it does not model return an error
+type Server struct {
+	mu       sync.Mutex
+	sessions []*session
+	current  *pane               // Pane the client is "running in" ($TMUX_PANE)
+	options  map[string]string   // Global options
+	bindings map[string][]string // "table key" -> command
+	hooks    map[string][]string // Hook name -> commands by array index
+	buffers  []*buffer           // Paste buffers, most recent first
+	calls    [][]string
+	nextWin  int
+	nextPane int
+	nextBuf  int
+}
+
+// windowWidth and windowHeight are the size of every new window
+const (
+	windowWidth  = 80
+	windowHeight = 24
+)
+
+type session struct {
+	name    string
+	windows []*window
+	active  *window
+}
+
+type window struct {
+	id      string
+	name    string
+	session *session
+	panes   []*pane
+	active  *pane
+	options map[string]string
+}
+
+type pane struct {
+	id       string
+	window   *window
+	command  string
+	content  string
+	input    []string
+	left     int // Position and size in the window; they belong to the
+	top      int // position, so swap-pane exchanges them
+	width    int
+	height   int
+	options  map[string]string
+	pipe     string // pipe-pane command ("" = not piped)
+	cursorX  int
+	cursorY  int
+	inMode   bool   // In copy mode
+	selected string // Text selected in copy mode ("" = no selection)
+}
+
+type buffer struct {
+	name    string
+	content string
+}
+
+// NewServer creates a fake server with a single session named "main"
+// containing one window and one pane, which becomes the current pane
+func NewServer() *Server {
+	s := &Server{
+		options:  make(map[string]string),
+		bindings: make(map[string][]string),
+		hooks:    make(map[string][]string),
+	}
+	sess := &session{name: "main"}
+	s.sessions = append(s.sessions, sess)
+	w := s.newWindow(sess, "shell", "")
+	s.current = w.active
+	return s
+}
+
+// Run executes a tmux command against the fake server
+func (s *Server) Run(args ...string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = append(s.calls, append([]string(nil), args...))
+	return s.run(args)
+}
+
+// run executes a command with s.mu held
+func (s *Server) run(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("no command")
+	}
+
+	name, rest := args[0], args[1:]
+	switch name {
+	case "display-message":
+		return s.displayMessage(rest)
+	case "rename-window":
+		return s.renameWindow(rest)
+	case "list-panes":
+		return s.listPanes(rest)
+	case "list-windows":
+		return s.listWindows(rest)
+	case "split-window":
+		return s.splitWindow(rest)
+	case "new-window":
+		return s.newWindowCmd(rest)
+	case "swap-pane":
+		return s.swapPane(rest)
+	case "break-pane", "breakp":
+		return s.breakPane(rest)
+	case "kill-pane":
+		return s.killPaneCmd(rest)
+	case "kill-window":
+		return s.killWindow(rest)
+	case "kill-session":
+		return s.killSession(rest)
+	case "has-session":
+		return s.hasSession(rest)
+	case "new-session":
+		return s.newSession(rest)
+	case "select-pane":
+		return s.selectPane(rest)
+	case "select-window":
+		return s.selectWindow(rest)
+	case "select-layout", "resize-pane", "refresh-client", "display-popup", "confirm-before":
+		_, err := s.resolveIgnoring(rest, "tcxy")
+		return "", err
+	case "set-option", "set-window-option", "setw", "set":
+		return s.setOption(name, rest)
+	case "show-options", "show-window-options", "show":
+		return s.showOptions(name, rest)
+	case "bind-key", "bind":
+		return s.bindKey(rest)
+	case "unbind-key", "unbind":
+		return s.unbindKey(rest)
+	case "list-keys", "lsk":
+		return s.listKeys(rest)
+	case "source-file", "source":
+		return s.sourceFile(rest)
+	case "capture-pane":
+		return s.capturePane(rest)
+	case "send-keys", "send":
+		return s.sendKeys(rest)
+	case "respawn-pane":
+		return s.respawnPane(rest)
+	case "pipe-pane", "pipep":
+		return s.pipePane(rest)
+	case "load-buffer", "loadb":
+		return s.loadBuffer(rest)
+	case "paste-buffer", "pasteb":
+		return s.pasteBuffer(rest)
+	case "show-buffer", "showb":
+		return s.showBuffer(rest)
+	case "delete-buffer", "deleteb":
+		return s.deleteBuffer(rest)
+	case "list-buffers", "lsb":
+		return s.listBuffers(rest)
+	case "set-hook":
+		return s.setHook(rest)
+	case "show-hooks":
+		return s.showHooks(rest)
+	}
+	return "", fmt.Errorf("unknown command: %s", name)
+}
+
+// Calls returns every command run against the server, in order
+func (s *Server) Calls() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	calls := make([][]string, len(s.calls))
+	for i, c := range s.calls {
+		calls[i] = append([]string(nil), c...)
+	}
+	return calls
+}
+
+// CurrentPane returns the ID of the pane the client is running in
+func (s *Server) CurrentPane() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current.id
+}
+
+// WindowIDs returns the IDs of all windows across all sessions
+func (s *Server) WindowIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	for _, sess := range s.sessions {
+		for _, w := range sess.windows {
+			ids = append(ids, w.id)
+		}
+	}
+	return ids
+}
+
+// WindowName returns the name of a window, or "" if it does not exist
+func (s *Server) WindowName(windowID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if w := s.findWindow(windowID); w != nil {
+		return w.name
+	}
+	return ""
+}
+
+// PaneIDs returns the pane IDs in a window in layout order
+func (s *Server) PaneIDs(windowID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := s.findWindow(windowID)
+	if w == nil {
+		return nil
+	}
+	ids := make([]string, len(w.panes))
+	for i, p := range w.panes {
+		ids[i] = p.id
+	}
+	return ids
+}
+
+// PaneWindow returns the ID of the window containing a pane, or "" if the
+// pane does not exist
+func (s *Server) PaneWindow(paneID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p := s.findPane(paneID); p != nil {
+		return p.window.id
+	}
+	return ""
+}
+
+// PaneCommand returns the command a pane was started with
+func (s *Server) PaneCommand(paneID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p := s.findPane(paneID); p != nil {
+		return p.command
+	}
+	return ""
+}
+
+// SetPaneContent sets the text returned by capture-pane for a pane
+func (s *Server) SetPaneContent(paneID, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p := s.findPane(paneID); p != nil {
+		p.content = content
+	}
+}
+
+// PipeCommand returns the command a pane's output is piped into with
+// pipe-pane, or "" if it is not piped
+func (s *Server) PipeCommand(paneID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p := s.findPane(paneID); p != nil {
+		return p.pipe
+	}
+	return ""
+}
+
+// SetCursor moves a pane's cursor, y counting from the top of the screen
+func (s *Server) SetCursor(paneID string, x, y int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p := s.findPane(paneID); p != nil {
+		p.cursorX, p.cursorY = x, y
+	}
+}
+
+// SetSelection puts a pane in copy mode with text selected, or none if
+// text is ""
+func (s *Server) SetSelection(paneID, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p := s.findPane(paneID); p != nil {
+		p.inMode, p.selected = true, text
+	}
+}
+
+// SentKeys returns the keys sent to a pane with send-keys
+func (s *Server) SentKeys(paneID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p := s.findPane(paneID); p != nil {
+		return append([]string(nil), p.input...)
+	}
+	return nil
+}
+
+// KillPane removes a pane as if its process had exited
+func (s *Server) KillPane(paneID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p := s.findPane(paneID); p != nil {
+		s.removePane(p)
+	}
+}
+
+// Option returns a global option value
+func (s *Server) Option(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.options[name]
+}
+
+// PaneOption returns a pane option value
+func (s *Server) PaneOption(paneID, name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p := s.findPane(paneID); p != nil {
+		return p.options[name]
+	}
+	return ""
+}
+
+// Binding returns the command bound to a key in the given table
+func (s *Server) Binding(table, key string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bindings[table+" "+key]...)
+}
+
+// Hooks returns the non-empty commands installed for a hook
+func (s *Server) Hooks(name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var cmds []string
+	for _, cmd := range s.hooks[name] {
+		if cmd != "" {
+			cmds = append(cmds, cmd)
+		}
+	}
+	return cmds
+}
+
+// --- model helpers ---
+
+func (s *Server) newWindow(sess *session, name, command string) *window {
+	w := &window{
+		id:      fmt.Sprintf("@%d", s.nextWin),
+		name:    name,
+		session: sess,
+		options: make(map[string]string),
+	}
+	s.nextWin++
+	p := s.newPane(w, command)
+	w.panes = []*pane{p}
+	w.active = p
+	sess.windows = append(sess.windows, w)
+	if sess.active == nil {
+		sess.active = w
+	}
+	return w
+}
+
+func (s *Server) newPane(w *window, command string) *pane {
+	p := &pane{
+		id:      fmt.Sprintf("%%%d", s.nextPane),
+		window:  w,
+		command: command,
+		width:   windowWidth,
+		height:  windowHeight,
+		options: make(map[string]string),
+	}
+	s.nextPane++
+	return p
+}
+
+func (s *Server) removePane(p *pane) {
+	w := p.window
+	for i, wp := range w.panes {
+		if wp == p {
+			w.panes = append(w.panes[:i], w.panes[i+1:]...)
+			break
+		}
+	}
+	if w.active == p && len(w.panes) > 0 {
+		w.active = w.panes[0]
+	}
+	if len(w.panes) == 0 {
+		s.removeWindow(w)
+	}
+}
+
+func (s *Server) removeWindow(w *window) {
+	sess := w.session
+	for i, sw := range sess.windows {
+		if sw == w {
+			sess.windows = append(sess.windows[:i], sess.windows[i+1:]...)
+			break
+		}
+	}
+	if sess.active == w {
+		sess.active = nil
+		if len(sess.windows) > 0 {
+			sess.active = sess.windows[0]
+		}
+	}
+	if len(sess.windows) == 0 {
+		s.removeSession(sess)
+	}
+}
+
+func (s *Server) removeSession(sess *session) {
+	for i, ss := range s.sessions {
+		if ss == sess {
+			s.sessions = append(s.sessions[:i], s.sessions[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *Server) findPane(id string) *pane {
+	for _, sess := range s.sessions {
+		for _, w := range sess.windows {
+			for _, p := range w.panes {
+				if p.id == id {
+					return p
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) findWindow(id string) *window {
+	for _, sess := range s.sessions {
+		for _, w := range sess.windows {
+			if w.id == id {
+				return w
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) findSession(name string) *session {
+	for _, sess := range s.sessions {
+		if sess.name == name {
+			return sess
+		}
+	}
+	return nil
+}
+
+// resolve maps a tmux target to a pane
+// Supported forms: "" (current pane), %pane, @window, session, window name,
+// and [session:]window[.index]
+func (s *Server) resolve(target string) (*pane, error) {
+	if target == "" {
+		if s.current == nil || s.findPane(s.current.id) == nil {
+			return nil, fmt.Errorf("no current target")
+		}
+		return s.current, nil
+	}
+	if strings.HasPrefix(target, "%") {
+		if p := s.findPane(target); p != nil {
+			return p, nil
+		}
+		return nil, fmt.Errorf("can't find pane: %s", target)
+	}
+	if strings.HasPrefix(target, "@") {
+		if w := s.findWindow(target); w != nil {
+			return w.active, nil
+		}
+		return nil, fmt.Errorf("can't find window: %s", target)
+	}
+
+	sess := s.currentSession()
+	winPart := target
+	if i := strings.Index(target, ":"); i >= 0 {
+		if sess = s.findSession(target[:i]); sess == nil {
+			return nil, fmt.Errorf("can't find session: %s", target[:i])
+		}
+		winPart = target[i+1:]
+	} else if ss := s.findSession(target); ss != nil {
+		if ss.active == nil {
+			return nil, fmt.Errorf("session has no windows: %s", target)
+		}
+		return ss.active.active, nil
+	}
+	if sess == nil {
+		return nil, fmt.Errorf("no current session")
+	}
+
+	paneIdx := -1
+	if i := strings.LastIndex(winPart, "."); i >= 0 {
+		n, err := strconv.Atoi(winPart[i+1:])
+		if err == nil {
+			paneIdx = n
+			winPart = winPart[:i]
+		}
+	}
+
+	var w *window
+	if winPart == "" {
+		w = sess.active
+	} else if strings.HasPrefix(winPart, "@") {
+		w = s.findWindow(winPart)
+	} else {
+		for _, sw := range sess.windows {
+			if sw.name == winPart {
+				w = sw
+				break
+			}
+		}
+	}
+	if w == nil {
+		return nil, fmt.Errorf("can't find window: %s", winPart)
+	}
+	if paneIdx < 0 {
+		return w.active, nil
+	}
+	if paneIdx >= len(w.panes) {
+		return nil, fmt.Errorf("can't find pane: %d", paneIdx)
+	}
+	return w.panes[paneIdx], nil
+}
+
+func (s *Server) currentSession() *session {
+	if s.current != nil && s.findPane(s.current.id) != nil {
+		return s.current.window.session
+	}
+	if len(s.sessions) > 0 {
+		return s.sessions[0]
+	}
+	return nil
+}
+
+// resolveIgnoring parses flags and resolves -t, ignoring everything else
+func (s *Server) resolveIgnoring(args []string, valueFlags string) (*pane, error) {
+	flags, _ := parseArgs(args, valueFlags)
+	return s.resolve(flags.get('t'))
+}
+
+// --- format expansion ---
+
+var formatVar = regexp.MustCompile(`#\{([^{}]*)\}`)
+
+func (s *Server) expand(format string, p *pane) string {
+	return formatVar.ReplaceAllStringFunc(format, func(m string) string {
+		return s.formatValue(m[2:len(m)-1], p)
+	})
+}
+
+func (s *Server) formatValue(name string, p *pane) string {
+	if strings.HasPrefix(name, "@") {
+		if v, ok := p.options[name]; ok {
+			return v
+		}
+		if v, ok := p.window.options[name]; ok {
+			return v
+		}
+		return s.options[name]
+	}
+
+	switch name {
+	case "pane_id":
+		return p.id
+	case "pane_index":
+		for i, wp := range p.window.panes {
+			if wp == p {
+				return strconv.Itoa(i)
+			}
+		}
+	case "pane_left":
+		return strconv.Itoa(p.left)
+	case "pane_top":
+		return strconv.Itoa(p.top)
+	case "pane_width":
+		return strconv.Itoa(p.width)
+	case "pane_height":
+		return strconv.Itoa(p.height)
+	case "pane_current_command", "pane_start_command":
+		return p.command
+	case "pane_pipe":
+		if p.pipe != "" {
+			return "1"
+		}
+		return "0"
+	case "pane_in_mode":
+		return boolFormat(p.inMode)
+	case "selection_present":
+		return boolFormat(p.selected != "")
+	case "cursor_x":
+		return strconv.Itoa(p.cursorX)
+	case "cursor_y":
+		return strconv.Itoa(p.cursorY)
+	case "pane_dead", "history_size", "alternate_on":
+		return "0"
+	case "window_id":
+		return p.window.id
+	case "window_name":
+		return p.window.name
+	case "window_index":
+		for i, w := range p.window.session.windows {
+			if w == p.window {
+				return strconv.Itoa(i)
+			}
+		}
+	case "window_panes":
+		return strconv.Itoa(len(p.window.panes))
+	case "session_name":
+		return p.window.session.name
+	case "socket_path":
+		return "/tmp/tmuxtest/default"
+	case "version":
+		return "fake"
+	}
+	// Other names are global options, e.g. #{status-left}
+	return s.options[name]
+}
+
+// boolFormat returns a format's value for a flag
+func boolFormat(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// --- commands ---
+
+func (s *Server) displayMessage(args []string) (string, error) {
+	flags, rest := parseArgs(args, "cdt")
+	p, err := s.resolve(flags.get('t'))
+	if err != nil {
+		return "", err
+	}
+	if !flags.has('p') {
+		return "", nil
+	}
+	return s.expand(strings.Join(rest, " "), p), nil
+}
+
+func (s *Server) renameWindow(args []string) (string, error) {
+	flags, rest := parseArgs(args, "t")
+	p, err := s.resolve(flags.get('t'))
+	if err != nil {
+		return "", err
+	}
+	if len(rest) == 0 {
+		return "", fmt.Errorf("rename-window: missing name")
+	}
+	p.window.name = rest[0]
+	return "", nil
+}
+
+func (s *Server) listPanes(args []string) (string, error) {
+	flags, _ := parseArgs(args, "Fft")
+	format := flags.get('F')
+	if format == "" {
+		format = "#{pane_id}"
+	}
+
+	var panes []*pane
+	switch {
+	case flags.has('a'):
+		for _, sess := range s.sessions {
+			for _, w := range sess.windows {
+				panes = append(panes, w.panes...)
+			}
+		}
+	case flags.has('s'):
+		p, err := s.resolve(flags.get('t'))
+		if err != nil {
+			return "", err
+		}
+		for _, w := range p.window.session.windows {
+			panes = append(panes, w.panes...)
+		}
+	default:
+		p, err := s.resolve(flags.get('t'))
+		if err != nil {
+			return "", err
+		}
+		panes = p.window.panes
+	}
+
+	lines := make([]string, len(panes))
+	for i, p := range panes {
+		lines[i] = s.expand(format, p)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (s *Server) listWindows(args []string) (string, error) {
+	flags, _ := parseArgs(args, "Fft")
+	format := flags.get('F')
+	if format == "" {
+		format = "#{window_id}"
+	}
+
+	var windows []*window
+	if flags.has('a') {
+		for _, sess := range s.sessions {
+			windows = append(windows, sess.windows...)
+		}
+	} else {
+		p, err := s.resolve(flags.get('t'))
+		if err != nil {
+			return "", err
+		}
+		windows = p.window.session.windows
+	}
+
+	lines := make([]string, len(windows))
+	for i, w := range windows {
+		lines[i] = s.expand(format, w.active)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (s *Server) splitWindow(args []string) (string, error) {
+	flags, rest := parseArgs(args, "celpFt")
+	target, err := s.resolve(flags.get('t'))
+	if err != nil {
+		return "", err
+	}
+
+	w := target.window
+	p := s.newPane(w, strings.Join(rest, " "))
+	splitGeometry(target, p, flags)
+	i := indexOf(w.panes, target)
+	if flags.has('b') {
+		i--
+	}
+	w.panes = append(w.panes[:i+1], append([]*pane{p}, w.panes[i+1:]...)...)
+	if !flags.has('d') {
+		w.active = p
+	}
+
+	if flags.has('P') {
+		format := flags.get('F')
+		if format == "" {
+			format = "#{session_name}:#{window_index}.#{pane_index}"
+		}
+		return s.expand(format, p), nil
+	}
+	return "", nil
+}
+
+// splitGeometry divides target's area between it and the new pane p: side by
+// side with -h, stacked otherwise, p after target unless -b
+// The size comes from -l (cells or a percentage) or -p, defaulting to half
+func splitGeometry(target, p *pane, flags argFlags) {
+	total := target.height
+	if flags.has('h') {
+		total = target.width
+	}
+	size := total / 2
+	if v := flags.get('l'); v != "" {
+		if pct, ok := strings.CutSuffix(v, "%"); ok {
+			n, _ := strconv.Atoi(pct)
+			size = total * n / 100
+		} else {
+			size, _ = strconv.Atoi(v)
+		}
+	} else if v := flags.get('p'); v != "" {
+		n, _ := strconv.Atoi(v)
+		size = total * n / 100
+	}
+	// One cell goes to the border between the panes
+	if size > total-2 {
+		size = total - 2
+	}
+	if size < 1 {
+		size = 1
+	}
+	rest := total - size - 1
+
+	p.left, p.top, p.width, p.height = target.left, target.top, target.width, target.height
+	if flags.has('h') {
+		target.width, p.width = rest, size
+		if flags.has('b') {
+			target.left += size + 1
+		} else {
+			p.left += rest + 1
+		}
+	} else {
+		target.height, p.height = rest, size
+		if flags.has('b') {
+			target.top += size + 1
+		} else {
+			p.top += rest + 1
+		}
+	}
+}
+
+func (s *Server) newWindowCmd(args []string) (string, error) {
+	flags, rest := parseArgs(args, "cenFt")
+	sess := s.currentSession()
+	if t := flags.get('t'); t != "" {
+		p, err := s.resolve(t)
+		if err != nil {
+			return "", err
+		}
+		sess = p.window.session
+	}
+	if sess == nil {
+		return "", fmt.Errorf("no current session")
+	}
+
+	w := s.newWindow(sess, flags.get('n'), strings.Join(rest, " "))
+	if !flags.has('d') {
+		sess.active = w
+	}
+
+	if flags.has('P') {
+		format := flags.get('F')
+		if format == "" {
+			format = "#{session_name}:#{window_index}"
+		}
+		return s.expand(format, w.active), nil
+	}
+	return "", nil
+}
+
+func (s *Server) swapPane(args []string) (string, error) {
+	flags, _ := parseArgs(args, "st")
+	src, err := s.resolve(flags.get('s'))
+	if err != nil {
+		return "", err
+	}
+	dst, err := s.resolve(flags.get('t'))
+	if err != nil {
+		return "", err
+	}
+	if src == dst {
+		return "", nil
+	}
+
+	sw, dw := src.window, dst.window
+	si, di := indexOf(sw.panes, src), indexOf(dw.panes, dst)
+	sw.panes[si], dw.panes[di] = dst, src
+	src.window, dst.window = dw, sw
+	src.left, dst.left = dst.left, src.left
+	src.top, dst.top = dst.top, src.top
+	src.width, dst.width = dst.width, src.width
+	src.height, dst.height = dst.height, src.height
+
+	// The active pane follows the position, as in tmux
+	switch {
+	case sw == dw:
+	case sw.active == src:
+		sw.active = dst
+		if dw.active == dst {
+			dw.active = src
+		}
+	case dw.active == dst:
+		dw.active = src
+	}
+	return "", nil
+}
+
+// breakPane handles break-pane [-d] [-s pane] [-n name] [-P] [-F format],
+// moving the pane into a new window of its session
+// A pane that is alone in its window keeps the window, which is renamed
+func (s *Server) breakPane(args []string) (string, error) {
+	flags, _ := parseArgs(args, "FnstC")
+	p, err := s.resolve(flags.get('s'))
+	if err != nil {
+		return "", err
+	}
+
+	w := p.window
+	if len(w.panes) > 1 {
+		sess := w.session
+		w.panes = append(w.panes[:indexOf(w.panes, p)], w.panes[indexOf(w.panes, p)+1:]...)
+		if w.active == p {
+			w.active = w.panes[0]
+		}
+
+		w = &window{
+			id:      fmt.Sprintf("@%d", s.nextWin),
+			session: sess,
+			panes:   []*pane{p},
+			active:  p,
+			options: make(map[string]string),
+		}
+		s.nextWin++
+		sess.windows = append(sess.windows, w)
+		p.window = w
+		p.left, p.top, p.width, p.height = 0, 0, windowWidth, windowHeight
+	}
+	if flags.has('n') {
+		w.name = flags.get('n')
+	}
+	if !flags.has('d') {
+		w.session.active = w
+	}
+
+	if flags.has('P') {
+		format := flags.get('F')
+		if format == "" {
+			format = "#{session_name}:#{window_index}.#{pane_index}"
+		}
+		return s.expand(format, p), nil
+	}
+	return "", nil
+}
+
+func (s *Server) killPaneCmd(args []string) (string, error) {
+	p, err := s.resolveIgnoring(args, "t")
+	if err != nil {
+		return "", err
+	}
+	s.removePane(p)
+	return "", nil
+}
+
+func (s *Server) killWindow(args []string) (string, error) {
+	p, err := s.resolveIgnoring(args, "t")
+	if err != nil {
+		return "", err
+	}
+	s.removeWindow(p.window)
+	return "", nil
+}
+
+func (s *Server) killSession(args []string) (string, error) {
+	p, err := s.resolveIgnoring(args, "t")
+	if err != nil {
+		return "", err
+	}
+	s.removeSession(p.window.session)
+	return "", nil
+}
+
+func (s *Server) hasSession(args []string) (string, error) {
+	flags, _ := parseArgs(args, "t")
+	name := strings.TrimPrefix(flags.get('t'), "=")
+	if s.findSession(name) == nil {
+		return "", fmt.Errorf("can't find session: %s", name)
+	}
+	return "", nil
+}
+
+func (s *Server) newSession(args []string) (string, error) {
+	flags, rest := parseArgs(args, "censtxyF")
+	name := flags.get('s')
+	if name == "" {
+		name = strconv.Itoa(len(s.sessions))
+	}
+	if s.findSession(name) != nil {
+		return "", fmt.Errorf("duplicate session: %s", name)
+	}
+
+	sess := &session{name: name}
+	s.sessions = append(s.sessions, sess)
+	w := s.newWindow(sess, flags.get('n'), strings.Join(rest, " "))
+
+	if flags.has('P') {
+		format := flags.get('F')
+		if format == "" {
+			format = "#{session_name}:"
+		}
+		return s.expand(format, w.active), nil
+	}
+	return "", nil
+}
+
+func (s *Server) selectPane(args []string) (string, error) {
+	p, err := s.resolveIgnoring(args, "tT")
+	if err != nil {
+		return "", err
+	}
+	p.window.active = p
+	return "", nil
+}
+
+func (s *Server) selectWindow(args []string) (string, error) {
+	p, err := s.resolveIgnoring(args, "t")
+	if err != nil {
+		return "", err
+	}
+	p.window.session.active = p.window
+	return "", nil
+}
+
+func (s *Server) setOption(cmd string, args []string) (string, error) {
+	flags, rest := parseArgs(args, "t")
+	if len(rest) == 0 {
+		return "", fmt.Errorf("%s: missing option name", cmd)
+	}
+
+	opts, err := s.optionScope(cmd, flags)
+	if err != nil {
+		return "", err
+	}
+
+	name := rest[0]
+	if flags.has('u') {
+		delete(opts, name)
+		return "", nil
+	}
+	value := ""
+	if len(rest) > 1 {
+		value = rest[1]
+	}
+	if flags.has('o') {
+		if _, ok := opts[name]; ok {
+			return "", nil
+		}
+	}
+	if flags.has('a') {
+		value = opts[name] + value
+	}
+	opts[name] = value
+	return "", nil
+}
+
+func (s *Server) showOptions(cmd string, args []string) (string, error) {
+	flags, rest := parseArgs(args, "t")
+	opts, err := s.optionScope(cmd, flags)
+	if err != nil {
+		return "", err
+	}
+
+	if len(rest) > 0 {
+		value := opts[rest[0]]
+		if flags.has('v') {
+			return value, nil
+		}
+		return rest[0] + " " + value, nil
+	}
+
+	var lines []string
+	for name, value := range opts {
+		if flags.has('v') {
+			lines = append(lines, value)
+		} else {
+			lines = append(lines, name+" "+value)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// optionScope returns the option map selected by -g/-w/-p and -t
+func (s *Server) optionScope(cmd string, flags argFlags) (map[string]string, error) {
+	window := flags.has('w') || strings.Contains(cmd, "window") || cmd == "setw"
+	if flags.has('g') && !flags.has('p') {
+		return s.options, nil
+	}
+	if !flags.has('p') && !window {
+		return s.options, nil
+	}
+
+	p, err := s.resolve(flags.get('t'))
+	if err != nil {
+		return nil, err
+	}
+	if flags.has('p') {
+		return p.options, nil
+	}
+	return p.window.options, nil
+}
+
+func (s *Server) bindKey(args []string) (string, error) {
+	flags, rest := parseArgs(args, "NT")
+	if len(rest) == 0 {
+		return "", fmt.Errorf("bind-key: missing key")
+	}
+	s.bindings[keyTable(flags)+" "+rest[0]] = append([]string(nil), rest[1:]...)
+	return "", nil
+}
+
+func (s *Server) unbindKey(args []string) (string, error) {
+	flags, rest := parseArgs(args, "T")
+	if len(rest) == 0 {
+		return "", fmt.Errorf("unbind-key: missing key")
+	}
+	delete(s.bindings, keyTable(flags)+" "+rest[0])
+	return "", nil
+}
+
+// listKeys handles list-keys [-T table] [key], printing bindings the way
+// tmux does so they can be fed back through source-file
+func (s *Server) listKeys(args []string) (string, error) {
+	flags, rest := parseArgs(args, "T")
+	table := flags.get('T')
+	if table == "" {
+		table = "prefix"
+	}
+
+	var keys []string
+	if len(rest) > 0 {
+		if _, ok := s.bindings[table+" "+rest[0]]; !ok {
+			return "", fmt.Errorf("unknown key: %s", rest[0])
+		}
+		keys = []string{rest[0]}
+	} else {
+		for binding := range s.bindings {
+			if t, key, _ := strings.Cut(binding, " "); t == table {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+	}
+
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		words := []string{"bind-key", "-T", table, key}
+		for _, arg := range s.bindings[table+" "+key] {
+			words = append(words, quoteArg(arg))
+		}
+		lines[i] = strings.Join(words, " ")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// quoteArg quotes a command argument for list-keys output
+func quoteArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\$#%;{}") {
+		return arg
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`)
+	return `"` + r.Replace(arg) + `"`
+}
+
+// sourceFile handles source-file path, running each command line in it
+func (s *Server) sourceFile(args []string) (string, error) {
+	_, rest := parseArgs(args, "t")
+	if len(rest) != 1 {
+		return "", fmt.Errorf("source-file: missing path")
+	}
+	data, err := os.ReadFile(rest[0])
+	if err != nil {
+		return "", fmt.Errorf("source-file: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words, err := splitCommand(line)
+		if err != nil {
+			return "", fmt.Errorf("source-file: %w", err)
+		}
+		if _, err := s.run(words); err != nil {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// splitCommand splits a tmux command line into words, handling single
+// quotes and double quotes with backslash escapes
+func splitCommand(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote in %q", line)
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+				}
+				word.WriteByte(line[i])
+			}
+			if i == len(line) {
+				return nil, fmt.Errorf("unterminated quote in %q", line)
+			}
+			inWord = true
+		case c == '\\' && i+1 < len(line):
+			i++
+			word.WriteByte(line[i])
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+func keyTable(flags argFlags) string {
+	if flags.has('n') {
+		return "root"
+	}
+	if t := flags.get('T'); t != "" {
+		return t
+	}
+	return "prefix"
+}
+
+func (s *Server) capturePane(args []string) (string, error) {
+	flags, _ := parseArgs(args, "bESt")
+	p, err := s.resolve(flags.get('t'))
+	if err != nil {
+		return "", err
+	}
+	if !flags.has('p') {
+		return "", nil
+	}
+
+	// Lines beyond the pane height are treated as scrollback history, so
+	// line 0 is the top of the visible area as in tmux
+	lines := strings.Split(p.content, "\n")
+	offset := len(lines) - p.height
+	if offset < 0 {
+		offset = 0
+	}
+	start, end := offset, len(lines)-1
+	if v := flags.get('S'); v != "" {
+		start = lineIndex(v, 0, offset)
+	}
+	if v := flags.get('E'); v != "" {
+		end = lineIndex(v, len(lines)-1, offset)
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end >= len(lines) {
+		end = len(lines) - 1
+	}
+	if start > end {
+		return "", nil
+	}
+	return strings.Join(lines[start:end+1], "\n"), nil
+}
+
+// lineIndex converts a capture-pane line number to an index into the content
+func lineIndex(v string, dash, offset int) int {
+	if v == "-" {
+		return dash
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return dash
+	}
+	return n + offset
+}
+
+func (s *Server) sendKeys(args []string) (string, error) {
+	flags, rest := parseArgs(args, "NtT")
+	p, err := s.resolve(flags.get('t'))
+	if err != nil {
+		return "", err
+	}
+	if flags.has('X') {
+		return s.copyModeCommand(p, rest)
+	}
+	p.input = append(p.input, rest...)
+	return "", nil
+}
+
+// copyModeCommand handles send-keys -X for the copy mode commands muxctl
+// uses; copying pushes the selection as a new automatic buffer
+func (s *Server) copyModeCommand(p *pane, args []string) (string, error) {
+	if !p.inMode {
+		return "", fmt.Errorf("not in a mode")
+	}
+	if len(args) != 1 || args[0] != "copy-selection-no-clear" {
+		return "", fmt.Errorf("send-keys -X: unsupported command %q", args)
+	}
+	if p.selected != "" {
+		s.setBuffer("", p.selected)
+	}
+	return "", nil
+}
+
+// findBuffer returns the named buffer, or the most recent one for ""
+func (s *Server) findBuffer(name string) (*buffer, error) {
+	for _, b := range s.buffers {
+		if name == "" || b.name == name {
+			return b, nil
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no buffers")
+	}
+	return nil, fmt.Errorf("no buffer %s", name)
+}
+
+// setBuffer stores a buffer on top of the stack; an empty name picks the
+// next automatic name, as in tmux
+func (s *Server) setBuffer(name, content string) {
+	if name == "" {
+		name = fmt.Sprintf("buffer%d", s.nextBuf)
+		s.nextBuf++
+	}
+	s.removeBuffer(name)
+	s.buffers = append([]*buffer{{name: name, content: content}}, s.buffers...)
+}
+
+func (s *Server) removeBuffer(name string) {
+	for i, b := range s.buffers {
+		if b.name == name {
+			s.buffers = append(s.buffers[:i], s.buffers[i+1:]...)
+			return
+		}
+	}
+}
+
+// loadBuffer handles load-buffer [-b name] path, reading the file from disk
+func (s *Server) loadBuffer(args []string) (string, error) {
+	flags, rest := parseArgs(args, "bt")
+	if len(rest) != 1 {
+		return "", fmt.Errorf("load-buffer: missing path")
+	}
+	data, err := os.ReadFile(rest[0])
+	if err != nil {
+		return "", fmt.Errorf("load-buffer: %w", err)
+	}
+	s.setBuffer(flags.get('b'), string(data))
+	return "", nil
+}
+
+// pasteBuffer handles paste-buffer [-d] [-p] [-b name] [-t pane]; the
+// buffer content is recorded as input to the pane
+func (s *Server) pasteBuffer(args []string) (string, error) {
+	flags, _ := parseArgs(args, "bst")
+	p, err := s.resolve(flags.get('t'))
+	if err != nil {
+		return "", err
+	}
+	b, err := s.findBuffer(flags.get('b'))
+	if err != nil {
+		return "", err
+	}
+	p.input = append(p.input, b.content)
+	if flags.has('d') {
+		s.removeBuffer(b.name)
+	}
+	return "", nil
+}
+
+func (s *Server) showBuffer(args []string) (string, error) {
+	flags, _ := parseArgs(args, "b")
+	b, err := s.findBuffer(flags.get('b'))
+	if err != nil {
+		return "", err
+	}
+	return b.content, nil
+}
+
+func (s *Server) deleteBuffer(args []string) (string, error) {
+	flags, _ := parseArgs(args, "b")
+	b, err := s.findBuffer(flags.get('b'))
+	if err != nil {
+		return "", err
+	}
+	s.removeBuffer(b.name)
+	return "", nil
+}
+
+// listBuffers handles list-buffers [-F format], most recent first; formats
+// may use #{buffer_name}, #{buffer_size} and #{buffer_sample}
+func (s *Server) listBuffers(args []string) (string, error) {
+	flags, _ := parseArgs(args, "Ff")
+	format := flags.get('F')
+	if format == "" {
+		format = "#{buffer_name}: #{buffer_size} bytes: \"#{buffer_sample}\""
+	}
+
+	lines := make([]string, len(s.buffers))
+	for i, b := range s.buffers {
+		lines[i] = formatVar.ReplaceAllStringFunc(format, func(m string) string {
+			switch m[2 : len(m)-1] {
+			case "buffer_name":
+				return b.name
+			case "buffer_size":
+				return strconv.Itoa(len(b.content))
+			case "buffer_sample":
+				return b.content
+			}
+			return ""
+		})
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// pipePane handles pipe-pane [-o] [-t pane] [command]; without a command the
+// pipe is closed, and -o only opens one if none is open
+func (s *Server) pipePane(args []string) (string, error) {
+	flags, rest := parseArgs(args, "t")
+	p, err := s.resolve(flags.get('t'))
+	if err != nil {
+		return "", err
+	}
+	command := strings.Join(rest, " ")
+	if flags.has('o') && p.pipe != "" && command != "" {
+		return "", nil
+	}
+	p.pipe = command
+	return "", nil
+}
+
+func (s *Server) respawnPane(args []string) (string, error) {
+	flags, rest := parseArgs(args, "cet")
+	p, err := s.resolve(flags.get('t'))
+	if err != nil {
+		return "", err
+	}
+	if len(rest) > 0 {
+		p.command = strings.Join(rest, " ")
+	}
+	return "", nil
+}
+
+// setHook handles set-hook [-a] [-u] name[index] [command]
+// Hooks are modelled as a single global array per name regardless of -g/-t
+func (s *Server) setHook(args []string) (string, error) {
+	flags, rest := parseArgs(args, "t")
+	if len(rest) == 0 {
+		return "", fmt.Errorf("set-hook: missing hook name")
+	}
+
+	name, index := rest[0], -1
+	if i := strings.Index(name, "["); i >= 0 && strings.HasSuffix(name, "]") {
+		n, err := strconv.Atoi(name[i+1 : len(name)-1])
+		if err != nil {
+			return "", fmt.Errorf("set-hook: bad index: %s", name)
+		}
+		name, index = name[:i], n
+	}
+
+	cmd := ""
+	if len(rest) > 1 {
+		cmd = rest[1]
+	}
+	hooks := s.hooks[name]
+	switch {
+	case flags.has('u') && index < 0:
+		delete(s.hooks, name)
+		return "", nil
+	case flags.has('u'):
+		cmd = ""
+	case flags.has('a'):
+		index = len(hooks)
+	case index < 0:
+		hooks, index = nil, 0
+	}
+	for len(hooks) <= index {
+		hooks = append(hooks, "")
+	}
+	hooks[index] = cmd
+	s.hooks[name] = hooks
+	return "", nil
+}
+
+// showHooks lists hooks as "name[index] command" lines
+func (s *Server) showHooks(args []string) (string, error) {
+	_, rest := parseArgs(args, "t")
+
+	var lines []string
+	for name, cmds := range s.hooks {
+		if len(rest) > 0 && rest[0] != name {
+			continue
+		}
+		for i, cmd := range cmds {
+			if cmd != "" {
+				lines = append(lines, fmt.Sprintf("%s[%d] %s", name, i, cmd))
+			}
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func indexOf(panes []*pane, p *pane) int {
+	for i, wp := range panes {
+		if wp == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// argFlags holds parsed command flags; flags without a value map to ""
+type argFlags map[byte][]string
+
+func (f argFlags) has(c byte) bool {
+	_, ok := f[c]
+	return ok
+}
+
+func (f argFlags) get(c byte) string {
+	if v := f[c]; len(v) > 0 {
+		return v[len(v)-1]
+	}
+	return ""
+}
+
+// parseArgs splits tmux-style arguments into flags and positional arguments
+// valueFlags lists the flag letters that take a value; parsing stops at the
+// first positional argument or "--"
+func parseArgs(args []string, valueFlags string) (argFlags, []string) {
+	flags := make(argFlags)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return flags, args[i+1:]
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			return flags, args[i:]
+		}
+		for j := 1; j < len(arg); j++ {
+			c := arg[j]
+			if !strings.ContainsRune(valueFlags, rune(c)) {
+				flags[c] = append(flags[c], "")
+				continue
+			}
+			value := arg[j+1:]
+			if value == "" && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			flags[c] = append(flags[c], value)
+			break
+		}
+	}
+	return flags, nil
+}
@@ -0,0 +1,185 @@
+package tmuxtest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// run runs a command on the fake and fails the test if it errors
+func run(t *testing.T, s *Server, args ...string) string {
+	t.Helper()
+	out, err := s.Run(args...)
+	if err != nil {
+		t.Fatalf("%s: %v", strings.Join(args, " "), err)
+	}
+	return out
+}
+
+func TestSplitWindowGeometry(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		first string // Original pane's left,top,width,height
+		new   string
+	}{
+		{"vertical", []string{"-v"}, "0,0,80,11", "0,12,80,12"},
+		{"horizontal", []string{"-h"}, "0,0,39,24", "40,0,40,24"},
+		{"before", []string{"-h", "-b"}, "41,0,39,24", "0,0,40,24"},
+		{"percent", []string{"-v", "-p", "25"}, "0,0,80,17", "0,18,80,6"},
+		{"cells", []string{"-h", "-l", "20"}, "0,0,59,24", "60,0,20,24"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			first := s.CurrentPane()
+			args := append(append([]string{"split-window"}, tt.args...), "-t", first, "-P", "-F", "#{pane_id}")
+			p := run(t, s, args...)
+
+			const format = "#{pane_left},#{pane_top},#{pane_width},#{pane_height}"
+			if got := run(t, s, "display-message", "-p", "-t", first, format); got != tt.first {
+				t.Errorf("original pane = %s, want %s", got, tt.first)
+			}
+			if got := run(t, s, "display-message", "-p", "-t", p, format); got != tt.new {
+				t.Errorf("new pane = %s, want %s", got, tt.new)
+			}
+
+			// -b puts the new pane first in layout order
+			panes := s.PaneIDs(s.PaneWindow(first))
+			want := []string{first, p}
+			if len(tt.args) > 1 && tt.args[1] == "-b" {
+				want = []string{p, first}
+			}
+			if !reflect.DeepEqual(panes, want) {
+				t.Errorf("panes = %v, want %v", panes, want)
+			}
+		})
+	}
+}
+
+func TestSwapPaneExchangesPositions(t *testing.T) {
+	s := NewServer()
+	top := s.CurrentPane()
+	bottom := run(t, s, "split-window", "-v", "-t", top, "-P", "-F", "#{pane_id}")
+	other := run(t, s, "new-window", "-d", "-P", "-F", "#{pane_id}")
+	mainWin := s.PaneWindow(top)
+
+	run(t, s, "swap-pane", "-s", bottom, "-t", other)
+
+	if got := s.PaneIDs(mainWin); !reflect.DeepEqual(got, []string{top, other}) {
+		t.Errorf("main window panes = %v, want [%s %s]", got, top, other)
+	}
+	if got := run(t, s, "display-message", "-p", "-t", other, "#{pane_top},#{pane_height}"); got != "12,12" {
+		t.Errorf("swapped-in pane geometry = %s, want 12,12", got)
+	}
+	if got := s.PaneWindow(bottom); got == mainWin {
+		t.Errorf("swapped-out pane is still in the main window")
+	}
+}
+
+func TestBreakPane(t *testing.T) {
+	s := NewServer()
+	first := s.CurrentPane()
+	second := run(t, s, "split-window", "-h", "-t", first, "-P", "-F", "#{pane_id}")
+
+	win := run(t, s, "break-pane", "-d", "-s", second, "-n", "stashed", "-P", "-F", "#{window_id}")
+	if win == s.PaneWindow(first) {
+		t.Fatalf("break-pane returned the original window")
+	}
+	if got := s.PaneWindow(second); got != win {
+		t.Errorf("pane is in %s, want %s", got, win)
+	}
+	if got := s.WindowName(win); got != "stashed" {
+		t.Errorf("window name = %q, want stashed", got)
+	}
+	if got := s.PaneIDs(s.PaneWindow(first)); !reflect.DeepEqual(got, []string{first}) {
+		t.Errorf("original window panes = %v, want [%s]", got, first)
+	}
+	if got := run(t, s, "display-message", "-p", "-t", second, "#{pane_left},#{pane_width}"); got != "0,80" {
+		t.Errorf("broken-out pane geometry = %s, want 0,80", got)
+	}
+}
+
+func TestBuffers(t *testing.T) {
+	s := NewServer()
+	dir := t.TempDir()
+	for _, b := range []struct{ name, content string }{{"first", "one"}, {"", "two"}} {
+		path := filepath.Join(dir, "buffer")
+		if err := os.WriteFile(path, []byte(b.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		args := []string{"load-buffer", path}
+		if b.name != "" {
+			args = []string{"load-buffer", "-b", b.name, path}
+		}
+		run(t, s, args...)
+	}
+
+	// Most recent first, with an automatic name for the unnamed buffer
+	if got := run(t, s, "list-buffers", "-F", "#{buffer_name}:#{buffer_size}"); got != "buffer0:3\nfirst:3" {
+		t.Errorf("list-buffers = %q", got)
+	}
+	if got := run(t, s, "show-buffer"); got != "two" {
+		t.Errorf("show-buffer = %q, want the top buffer", got)
+	}
+
+	run(t, s, "paste-buffer", "-d", "-b", "first", "-t", s.CurrentPane())
+	if got := s.SentKeys(s.CurrentPane()); !reflect.DeepEqual(got, []string{"one"}) {
+		t.Errorf("pasted %v, want [one]", got)
+	}
+	if got := run(t, s, "list-buffers", "-F", "#{buffer_name}"); got != "buffer0" {
+		t.Errorf("after paste-buffer -d, list-buffers = %q", got)
+	}
+}
+
+func TestCopyMode(t *testing.T) {
+	s := NewServer()
+	pane := s.CurrentPane()
+	if _, err := s.Run("send-keys", "-t", pane, "-X", "copy-selection-no-clear"); err == nil {
+		t.Errorf("copy-selection-no-clear outside copy mode succeeded")
+	}
+
+	s.SetSelection(pane, "selected text")
+	if got := run(t, s, "display-message", "-p", "-t", pane, "#{pane_in_mode}#{selection_present}"); got != "11" {
+		t.Errorf("mode and selection = %q, want 11", got)
+	}
+	run(t, s, "send-keys", "-t", pane, "-X", "copy-selection-no-clear")
+	if got := run(t, s, "show-buffer"); got != "selected text" {
+		t.Errorf("top buffer = %q, want the selection", got)
+	}
+	if got := s.SentKeys(pane); got != nil {
+		t.Errorf("copy mode commands reached the pane as keys %q", got)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	s := NewServer()
+	got := run(t, s, "display-message", "-p", "#{session_name} #{window_name} #{pane_index} #{alternate_on}")
+	if got != "main shell 0 0" {
+		t.Errorf("display-message = %q", got)
+	}
+}
+
+func TestListKeysRoundTrip(t *testing.T) {
+	s := NewServer()
+	bound := []string{"if-shell", "-F", "#{m:x,y}", `run-shell "echo 'a b' $HOME"`, "select-window -t ="}
+	run(t, s, append([]string{"bind-key", "-n", "MouseDown1Status"}, bound...)...)
+
+	line := run(t, s, "list-keys", "-T", "root", "MouseDown1Status")
+	if _, err := s.Run("list-keys", "-T", "root", "M-Enter"); err == nil || !strings.Contains(err.Error(), "unknown key") {
+		t.Errorf("list-keys of an unbound key: %v, want unknown key", err)
+	}
+
+	// Feeding the line back binds the same command
+	run(t, s, "unbind-key", "-n", "MouseDown1Status")
+	path := filepath.Join(t.TempDir(), "keys.conf")
+	if err := os.WriteFile(path, []byte("# saved\n"+line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	run(t, s, "source-file", path)
+	if got := s.Binding("root", "MouseDown1Status"); !reflect.DeepEqual(got, bound) {
+		t.Errorf("after source-file %q\nbinding = %q\nwant %q", line, got, bound)
+	}
+}